@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/dontagr/loyalty/internal/bootstrap"
+)
+
+const commandTimeout = 5 * time.Minute
+
+// runCommand поднимает минимальный fx-контейнер (конфиг, логгер, postgres) для одноразовых подкоманд.
+// Оставшиеся аргументы разбирает FlagEnricher, как при обычном запуске.
+func runCommand(args []string, fn func(ctx context.Context) error, opts ...fx.Option) error {
+	os.Args = append([]string{os.Args[0]}, args...)
+
+	app := fx.New(append([]fx.Option{
+		bootstrap.Config,
+		bootstrap.Logger,
+		bootstrap.Postgres,
+	}, opts...)...)
+	if err := app.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		_ = app.Stop(context.Background())
+	}()
+
+	return fn(ctx)
+}
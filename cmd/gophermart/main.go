@@ -1,17 +1,42 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"go.uber.org/fx"
 
 	"github.com/dontagr/loyalty/internal/bootstrap"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		var command func(args []string) error
+		switch os.Args[1] {
+		case "migrate":
+			command = runMigrate
+		case "reconcile":
+			command = runReconcile
+		case "role":
+			command = runRole
+		}
+		if command != nil {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	fx.New(
+		bootstrap.Migration,
 		bootstrap.Server,
 		bootstrap.Config,
 		bootstrap.Logger,
+		bootstrap.Tracing,
 		bootstrap.Postgres,
+		bootstrap.Metrics,
 		bootstrap.Store,
 		bootstrap.Route,
 		bootstrap.Service,
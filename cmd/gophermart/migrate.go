@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/dontagr/loyalty/internal/store/migrations"
+)
+
+const migrateUsage = "usage: gophermart migrate up|down [N]|status [-d dsn]"
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+
+	steps := 1
+	if command == "down" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of steps %q\n%s", args[0], migrateUsage)
+		}
+		steps, args = n, args[1:]
+	}
+
+	var migrator *migrations.Migrator
+	return runCommand(args, func(ctx context.Context) error {
+		switch command {
+		case "up":
+			return migrator.Up(ctx)
+		case "down":
+			return migrator.Down(ctx, steps)
+		case "status":
+			list, err := migrator.Status(ctx)
+			if err != nil {
+				return err
+			}
+			printStatus(list)
+
+			return nil
+		default:
+			return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+		}
+	},
+		fx.Provide(migrations.NewMigrator),
+		fx.Populate(&migrator),
+	)
+}
+
+func printStatus(list []*migrations.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range list {
+		state := "pending"
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Mismatch {
+			state = "checksum mismatch"
+		}
+		_, _ = fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	_ = w.Flush()
+}
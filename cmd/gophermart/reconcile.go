@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"go.uber.org/fx"
+
+	"github.com/dontagr/loyalty/internal/bootstrap"
+	"github.com/dontagr/loyalty/internal/service/reconciliation"
+)
+
+func runReconcile(args []string) error {
+	autoCorrect := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "-fix" || arg == "--fix" {
+			autoCorrect = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	var service *reconciliation.Service
+	return runCommand(rest, func(ctx context.Context) error {
+		run, err := service.Run(ctx, autoCorrect)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "USER\tLOGIN\tBALANCE\tEXPECTED\tDIFFERENCE\tCORRECTED")
+		for _, d := range run.Discrepancies {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%.2f\t%.2f\t%.2f\t%t\n", d.UserID, d.Login, float64(d.Balance)/100, float64(d.Expected)/100, float64(d.Difference())/100, d.Corrected)
+		}
+		_ = w.Flush()
+		fmt.Printf("run #%d: users=%d discrepancies=%d corrected=%d\n", run.ID, run.UsersChecked, len(run.Discrepancies), run.Corrected)
+
+		return nil
+	},
+		bootstrap.Store,
+		fx.Provide(reconciliation.NewReconciliationService),
+		fx.Populate(&service),
+	)
+}
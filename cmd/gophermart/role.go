@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/fx"
+
+	"github.com/dontagr/loyalty/internal/bootstrap"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const roleUsage = "usage: gophermart role LOGIN user|admin [-d dsn]"
+
+// runRole назначает роль пользователю. Это единственный способ получить первого администратора,
+// новая роль попадает в токен при следующем входе или обновлении токена.
+func runRole(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf(roleUsage)
+	}
+	login, role, args := args[0], args[1], args[2:]
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return fmt.Errorf("unknown role %q\n%s", role, roleUsage)
+	}
+
+	var store interfaces.UserStore
+	return runCommand(args, func(ctx context.Context) error {
+		updated, err := store.SetRole(ctx, login, role)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return fmt.Errorf("user %q not found", login)
+		}
+		fmt.Printf("user %s now has role %s\n", login, role)
+
+		return nil
+	},
+		bootstrap.Store,
+		fx.Populate(&store),
+	)
+}
@@ -0,0 +1,11 @@
+package bootstrap
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/dontagr/loyalty/internal/metrics"
+)
+
+var Metrics = fx.Options(
+	fx.Provide(metrics.NewMetrics),
+)
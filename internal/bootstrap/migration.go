@@ -0,0 +1,20 @@
+package bootstrap
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+
+	"github.com/dontagr/loyalty/internal/store/migrations"
+)
+
+var Migration = fx.Options(
+	fx.Provide(migrations.NewMigrator),
+	fx.Invoke(func(migrator *migrations.Migrator, lc fx.Lifecycle) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				return migrator.Up(ctx)
+			},
+		})
+	}),
+)
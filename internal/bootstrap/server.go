@@ -10,9 +10,11 @@ import (
 var Server = fx.Options(
 	fx.Provide(
 		httpserver.NewServer,
+		httpserver.NewAdminServer,
 	),
 	fx.Invoke(
 		func(*httpserver.HTTPServer) {},
+		func(*httpserver.AdminServer) {},
 		routing.InitRouting,
 	),
 )
@@ -3,8 +3,17 @@ package bootstrap
 import (
 	"go.uber.org/fx"
 
+	"github.com/dontagr/loyalty/internal/service/admin"
+	"github.com/dontagr/loyalty/internal/service/audit"
+	"github.com/dontagr/loyalty/internal/service/health"
 	"github.com/dontagr/loyalty/internal/service/jwt"
+	"github.com/dontagr/loyalty/internal/service/ledger"
 	"github.com/dontagr/loyalty/internal/service/order"
+	"github.com/dontagr/loyalty/internal/service/partner"
+	"github.com/dontagr/loyalty/internal/service/ratelimit"
+	"github.com/dontagr/loyalty/internal/service/reconciliation"
+	"github.com/dontagr/loyalty/internal/service/tier"
+	"github.com/dontagr/loyalty/internal/service/transfer"
 	"github.com/dontagr/loyalty/internal/service/transport"
 	"github.com/dontagr/loyalty/internal/service/user"
 	"github.com/dontagr/loyalty/internal/service/withdrawal"
@@ -17,5 +26,14 @@ var Service = fx.Options(
 		order.NewOrderService,
 		transport.NewHTTPManager,
 		withdrawal.NewWithdrawalService,
+		ledger.NewLedgerService,
+		reconciliation.NewReconciliationService,
+		ratelimit.NewLoginLimiter,
+		health.NewHealthService,
+		admin.NewAdminService,
+		audit.NewAuditService,
+		partner.NewPartnerService,
+		transfer.NewTransferService,
+		tier.NewTierService,
 	),
 )
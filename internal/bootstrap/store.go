@@ -2,9 +2,23 @@ package bootstrap
 
 import (
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
 	"github.com/dontagr/loyalty/internal/service/interfaces"
+	serviceRateLimit "github.com/dontagr/loyalty/internal/service/ratelimit"
+	"github.com/dontagr/loyalty/internal/store/audit"
+	"github.com/dontagr/loyalty/internal/store/idempotency"
+	"github.com/dontagr/loyalty/internal/store/ledger"
+	"github.com/dontagr/loyalty/internal/store/nonce"
 	"github.com/dontagr/loyalty/internal/store/order"
+	"github.com/dontagr/loyalty/internal/store/outbox"
+	"github.com/dontagr/loyalty/internal/store/ratelimit"
+	"github.com/dontagr/loyalty/internal/store/reconciliation"
+	"github.com/dontagr/loyalty/internal/store/session"
+	"github.com/dontagr/loyalty/internal/store/tier"
+	"github.com/dontagr/loyalty/internal/store/transfer"
 	"github.com/dontagr/loyalty/internal/store/user"
 	"github.com/dontagr/loyalty/internal/store/withdrawal"
 )
@@ -23,10 +37,65 @@ var Store = fx.Options(
 			withdrawal.NewWithdrawal,
 			fx.As(new(interfaces.WithdrawalStore)),
 		),
+		fx.Annotate(
+			outbox.NewOutbox,
+			fx.As(new(interfaces.OutboxStore)),
+		),
+		fx.Annotate(
+			ledger.NewLedger,
+			fx.As(new(interfaces.LedgerStore)),
+		),
+		fx.Annotate(
+			reconciliation.NewReconciliation,
+			fx.As(new(interfaces.ReconciliationStore)),
+		),
+		fx.Annotate(
+			session.NewSession,
+			fx.As(new(interfaces.SessionStore)),
+		),
+		fx.Annotate(
+			audit.NewAudit,
+			fx.As(new(interfaces.AuditStore)),
+		),
+		fx.Annotate(
+			idempotency.NewIdempotency,
+			fx.As(new(interfaces.IdempotencyStore)),
+		),
+		fx.Annotate(
+			transfer.NewTransfer,
+			fx.As(new(interfaces.TransferStore)),
+		),
+		fx.Annotate(
+			tier.NewTier,
+			fx.As(new(interfaces.TierStore)),
+		),
+		fx.Annotate(
+			nonce.NewNonce,
+			fx.As(new(interfaces.NonceStore)),
+		),
+		newRateLimitStore,
 	),
 	fx.Invoke(
 		func(interfaces.OrderStore) {},
 		func(interfaces.UserStore) {},
 		func(interfaces.WithdrawalStore) {},
+		func(interfaces.OutboxStore) {},
+		func(interfaces.LedgerStore) {},
+		func(interfaces.ReconciliationStore) {},
+		func(interfaces.SessionStore) {},
+		func(interfaces.RateLimitStore) {},
+		func(interfaces.AuditStore) {},
+		func(interfaces.IdempotencyStore) {},
+		func(interfaces.TransferStore) {},
+		func(interfaces.TierStore) {},
+		func(interfaces.NonceStore) {},
 	),
 )
+
+func newRateLimitStore(cfg *config.Config, log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) interfaces.RateLimitStore {
+	if cfg.RateLimit.Storage == "postgres" {
+		return ratelimit.NewRateLimit(log, dbpool)
+	}
+
+	return serviceRateLimit.NewMemoryStore()
+}
@@ -0,0 +1,13 @@
+package bootstrap
+
+import (
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+
+	"github.com/dontagr/loyalty/internal/tracing"
+)
+
+var Tracing = fx.Options(
+	fx.Provide(tracing.NewTracerProvider),
+	fx.Invoke(func(trace.TracerProvider) {}),
+)
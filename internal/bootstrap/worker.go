@@ -3,12 +3,28 @@ package bootstrap
 import (
 	"go.uber.org/fx"
 
+	"github.com/dontagr/loyalty/internal/service/publisher"
 	"github.com/dontagr/loyalty/internal/worker"
 )
 
 var Worker = fx.Options(
 	fx.Provide(
 		worker.NewUpdater,
+		publisher.NewPublisher,
+		worker.NewRelay,
+		worker.NewReconciler,
+		worker.NewAuditCleaner,
+		worker.NewExpirer,
+		worker.NewTierEvaluator,
+		worker.NewRateLimitCleaner,
+	),
+	fx.Invoke(
+		func(*worker.Updater) {},
+		func(*worker.Relay) {},
+		func(*worker.Reconciler) {},
+		func(*worker.AuditCleaner) {},
+		func(*worker.Expirer) {},
+		func(*worker.TierEvaluator) {},
+		func(*worker.RateLimitCleaner) {},
 	),
-	fx.Invoke(func(*worker.Updater) {}),
 )
@@ -0,0 +1,47 @@
+package config
+
+// Tier возвращает уровень по имени. Пользователь без уровня или с уровнем, удаленным из
+// конфигурации, получает начальный уровень. nil - уровни не настроены.
+func (l *Loyalty) Tier(name string) *Tier {
+	var base *Tier
+	for i := range l.Tiers {
+		tier := &l.Tiers[i]
+		if tier.Name == name {
+			return tier
+		}
+		if base == nil || tier.Threshold < base.Threshold {
+			base = tier
+		}
+	}
+
+	return base
+}
+
+// TierFor возвращает старший уровень, порог которого не превышает accrued баллов.
+func (l *Loyalty) TierFor(accrued float64) *Tier {
+	result := l.Tier("")
+	if result == nil {
+		return nil
+	}
+	for i := range l.Tiers {
+		tier := &l.Tiers[i]
+		if tier.Threshold <= accrued && tier.Threshold > result.Threshold {
+			result = tier
+		}
+	}
+
+	return result
+}
+
+// NextTier возвращает ближайший уровень выше переданного, nil - уровень уже старший.
+func (l *Loyalty) NextTier(current *Tier) *Tier {
+	var result *Tier
+	for i := range l.Tiers {
+		tier := &l.Tiers[i]
+		if tier.Threshold > current.Threshold && (result == nil || tier.Threshold < result.Threshold) {
+			result = tier
+		}
+	}
+
+	return result
+}
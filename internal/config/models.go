@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type Config struct {
 	Log             Logging         `json:"Logging"`
 	HTTPServer      HTTPServer      `json:"HttpServing"`
@@ -7,11 +9,123 @@ type Config struct {
 	Security        Security        `json:"Security"`
 	CalculateSystem CalculateSystem `json:"CalculateSystem"`
 	Service         Service         `json:"Service"`
+	Outbox          Outbox          `json:"Outbox"`
+	Reconciliation  Reconciliation  `json:"Reconciliation"`
+	RateLimit       RateLimit       `json:"RateLimit"`
+	Health          Health          `json:"Health"`
+	Tracing         Tracing         `json:"Tracing"`
+	Audit           Audit           `json:"Audit"`
+	Partner         Partner         `json:"Partner"`
+	Points          Points          `json:"Points"`
+	Transfer        Transfer        `json:"Transfer"`
+	Loyalty         Loyalty         `json:"Loyalty"`
+}
+
+// Loyalty.Tiers - уровни программы лояльности, пустой список отключает повышенные начисления.
+// TierInterval - период пересчета уровней в секундах, TierBatch - размер пачки пользователей.
+type Loyalty struct {
+	Tiers        []Tier `json:"Tiers" validate:"dive"`
+	TierInterval int    `json:"TierInterval"`
+	TierBatch    int    `json:"TierBatch"`
+}
+
+// Tier присваивается, когда начисления по заказам за последние 12 месяцев достигают Threshold баллов.
+// Multiplier применяется к начислению системы расчета по заказу.
+type Tier struct {
+	Name       string  `json:"Name" validate:"required,max=32"`
+	Threshold  float64 `json:"Threshold" validate:"gte=0"`
+	Multiplier float64 `json:"Multiplier" validate:"gte=1"`
+}
+
+// Transfer ограничивает переводы баллов одного пользователя за последние сутки: DailyAmount в баллах,
+// DailyCount - число переводов. 0 снимает ограничение.
+type Transfer struct {
+	DailyAmount float64 `json:"DailyAmount" validate:"gte=0"`
+	DailyCount  int     `json:"DailyCount" validate:"gte=0"`
+}
+
+// Points.ValidityDays - срок действия начисленных баллов в днях, 0 - баллы не сгорают.
+// ExpiringSoonDays - окно для сумм "скоро сгорят" в /api/user/balance, ExpiryInterval - период проверки в секундах.
+type Points struct {
+	ValidityDays     int `json:"ValidityDays" env:"POINTS_VALIDITY_DAYS" validate:"gte=0"`
+	ExpiringSoonDays int `json:"ExpiringSoonDays"`
+	ExpiryInterval   int `json:"ExpiryInterval"`
+	ExpiryBatch      int `json:"ExpiryBatch"`
+}
+
+// Partner.CallbackSecret подписывает обратные вызовы партнера, пустое значение отключает их.
+// SignatureTolerance - допустимое расхождение метки времени вызова в секундах.
+type Partner struct {
+	CallbackSecret     string `json:"CallbackSecret" env:"PARTNER_CALLBACK_SECRET"`
+	SignatureTolerance int    `json:"SignatureTolerance"`
+}
+
+// Audit.Retention задается в днях, 0 - хранить журнал бессрочно. CleanupInterval в секундах.
+type Audit struct {
+	Retention       int `json:"Retention" env:"AUDIT_RETENTION_DAYS" validate:"gte=0"`
+	CleanupInterval int `json:"CleanupInterval"`
+	CleanupBatch    int `json:"CleanupBatch"`
+}
+
+type Tracing struct {
+	Exporter    string  `json:"Exporter" env:"TRACING_EXPORTER" validate:"omitempty,oneof=none otlp stdout"`
+	Endpoint    string  `json:"Endpoint" env:"TRACING_ENDPOINT"`
+	ServiceName string  `json:"ServiceName"`
+	SampleRatio float64 `json:"SampleRatio" validate:"gte=0,lte=1"`
+}
+
+type Health struct {
+	// CheckTimeout задается в миллисекундах, UpdaterMaxLag - в секундах (по умолчанию три интервала опроса)
+	CheckTimeout  int `json:"CheckTimeout"`
+	UpdaterMaxLag int `json:"UpdaterMaxLag"`
 }
 
 type Service struct {
-	WorkerLimit     int `json:"WorkerLimit"`
-	UpdaterInterval int `json:"UpdaterInterval"`
+	WorkerLimit           int     `json:"WorkerLimit"`
+	UpdaterInterval       int     `json:"UpdaterInterval"`
+	AccrualMaxConcurrency int     `json:"AccrualMaxConcurrency"`
+	AccrualMinRate        float64 `json:"AccrualMinRate"`
+	AccrualMaxRate        float64 `json:"AccrualMaxRate"`
+	AccrualRateIncrease   float64 `json:"AccrualRateIncrease"`
+	AccrualDecreaseFactor float64 `json:"AccrualDecreaseFactor" validate:"gte=0,lt=1"`
+	AccrualLatencyTarget  int     `json:"AccrualLatencyTarget"`
+	AccrualRetryAfter     int     `json:"AccrualRetryAfter"`
+	BatchSize             int     `json:"BatchSize"`
+	RetryBaseInterval     int     `json:"RetryBaseInterval"`
+	RetryMaxInterval      int     `json:"RetryMaxInterval"`
+	OrderMaxAge           int     `json:"OrderMaxAge"`
+	LeaseTTL              int     `json:"LeaseTTL"`
+	ShutdownTimeout       int     `json:"ShutdownTimeout"`
+	// IdempotencyKeyTTL - сколько секунд хранится ответ на запрос с Idempotency-Key
+	IdempotencyKeyTTL int `json:"IdempotencyKeyTTL"`
+}
+
+type RateLimit struct {
+	Storage          string `json:"Storage" validate:"omitempty,oneof=memory postgres"`
+	LoginWindow      int    `json:"LoginWindow"`
+	LoginMaxFailures int    `json:"LoginMaxFailures"`
+	IPWindow         int    `json:"IPWindow"`
+	IPMaxFailures    int    `json:"IPMaxFailures"`
+	Lockout          int    `json:"Lockout"`
+	CleanupInterval  int    `json:"CleanupInterval"`
+}
+
+type Reconciliation struct {
+	Interval    int  `json:"Interval"`
+	AutoCorrect bool `json:"AutoCorrect" env:"RECONCILIATION_AUTO_CORRECT"`
+}
+
+type Outbox struct {
+	Sink          string `json:"Sink" env:"OUTBOX_SINK" validate:"omitempty,oneof=webhook file stdout"`
+	WebhookURL    string `json:"WebhookURL" env:"OUTBOX_WEBHOOK_URL" validate:"required_if=Sink webhook"`
+	FilePath      string `json:"FilePath" env:"OUTBOX_FILE_PATH" validate:"required_if=Sink file"`
+	Timeout       int    `json:"Timeout"`
+	Interval      int    `json:"Interval"`
+	BatchSize     int    `json:"BatchSize"`
+	MaxAttempts   int    `json:"MaxAttempts"`
+	RetryInterval int    `json:"RetryInterval"`
+	// LeaseTTL - на сколько секунд реплика забирает пачку событий на отправку
+	LeaseTTL int `json:"LeaseTTL"`
 }
 
 type CalculateSystem struct {
@@ -19,11 +133,22 @@ type CalculateSystem struct {
 }
 
 type DataBase struct {
-	DatabaseDsn string `json:"DatabaseDsn" env:"DATABASE_URI" flag:"d" validate:"required"`
+	DatabaseDsn          string `json:"DatabaseDsn" env:"DATABASE_URI" flag:"d" validate:"required"`
+	RetryMaxTries        int    `json:"RetryMaxTries"`
+	RetryInitialInterval int    `json:"RetryInitialInterval"`
+	RetryMaxInterval     int    `json:"RetryMaxInterval"`
+	BreakerThreshold     int    `json:"BreakerThreshold"`
+	BreakerCooldown      int    `json:"BreakerCooldown"`
 }
 
 type HTTPServer struct {
-	BindAddress string `json:"BindAddress" env:"RUN_ADDRESS" flag:"a" validate:"required"`
+	BindAddress    string   `json:"BindAddress" env:"RUN_ADDRESS" flag:"a" validate:"required"`
+	TrustedProxies []string `json:"TrustedProxies" validate:"dive,cidr"`
+	// AdminBindAddress - адрес для /metrics, /healthz и /readyz, пустое значение отключает служебный сервер
+	AdminBindAddress string `json:"AdminBindAddress" env:"ADMIN_RUN_ADDRESS"`
+	// QueryTimeout и EndpointTimeouts задаются в миллисекундах, ключ EndpointTimeouts - "METHOD /path" маршрута
+	QueryTimeout     int            `json:"QueryTimeout"`
+	EndpointTimeouts map[string]int `json:"EndpointTimeouts"`
 }
 
 type Logging struct {
@@ -31,5 +156,16 @@ type Logging struct {
 }
 
 type Security struct {
-	Key string `json:"key" validate:"required"`
+	Key                string       `json:"key" validate:"required_without=SigningKeys"`
+	SigningKeys        []SigningKey `json:"SigningKeys" validate:"dive"`
+	AccessTokenTTL     int          `json:"AccessTokenTTL"`
+	RefreshTokenTTL    int          `json:"RefreshTokenTTL"`
+	RevocationCacheTTL int          `json:"RevocationCacheTTL"`
+}
+
+type SigningKey struct {
+	ID         string    `json:"ID" validate:"required"`
+	Path       string    `json:"Path" validate:"required"`
+	ActiveFrom time.Time `json:"ActiveFrom"`
+	ExpiresAt  time.Time `json:"ExpiresAt"`
 }
@@ -0,0 +1,41 @@
+package pgretry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBreaker(2, 10*time.Second)
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.allow())
+	b.failure()
+	assert.NoError(t, b.allow())
+	b.failure()
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen)
+
+	now = now.Add(10 * time.Second)
+	assert.NoError(t, b.allow())
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen, "only one probe while half-open")
+
+	b.failure()
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen)
+
+	now = now.Add(10 * time.Second)
+	assert.NoError(t, b.allow())
+	b.success()
+	assert.NoError(t, b.allow())
+	assert.NoError(t, b.allow())
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker(0, time.Second)
+	for i := 0; i < 10; i++ {
+		b.failure()
+	}
+	assert.NoError(t, b.allow())
+}
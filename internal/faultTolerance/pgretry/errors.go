@@ -0,0 +1,63 @@
+package pgretry
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+	adminShutdown        = "57P01"
+	crashShutdown        = "57P02"
+	cannotConnectNow     = "57P03"
+	connectionException  = "08"
+)
+
+var ErrCircuitOpen = errors.New("база данных недоступна: автомат разомкнут")
+
+// isConnectionError отличает недоступность базы от ошибок самого запроса; только такие ошибки размыкают автомат.
+func isConnectionError(err error) bool {
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, connectionException) ||
+			pgErr.Code == adminShutdown ||
+			pgErr.Code == crashShutdown ||
+			pgErr.Code == cannotConnectNow
+	}
+
+	return pgconn.SafeToRetry(err)
+}
+
+func isRetryable(err error) bool {
+	if isConnectionError(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+	}
+
+	return false
+}
+
+// isRetryableTx дополнительно повторяет транзакцию, у которой соединение оборвалось до коммита:
+// незакоммиченная транзакция откатывается сервером целиком.
+func isRetryableTx(err error) bool {
+	if isRetryable(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
@@ -10,69 +10,92 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/labstack/gommon/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/tracing"
 )
 
 const (
-	maxRetries          = 3
-	retryInterval       = 5
-	maxBackoffInterval  = 10 * time.Second
-	randomizationFactor = 0
-	multiplier          = 1
+	defaultMaxTries        = 3
+	defaultInitialInterval = 500 * time.Millisecond
+	defaultMaxInterval     = 5 * time.Second
+	defaultBreakerCooldown = 10 * time.Second
+	multiplier             = 2
 )
 
+var tracer = otel.Tracer("github.com/dontagr/loyalty/internal/faultTolerance/pgretry")
+
 type PgxRetry struct {
-	dbpool   *pgxpool.Pool
-	duration []int
-	log      *zap.SugaredLogger
+	dbpool          *pgxpool.Pool
+	breaker         *breaker
+	maxTries        uint
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	log             *zap.SugaredLogger
 }
 
-func NewPgxRetry(conn *pgxpool.Pool, log *zap.SugaredLogger) *PgxRetry {
+func NewPgxRetry(conn *pgxpool.Pool, log *zap.SugaredLogger, cfg *config.Config) *PgxRetry {
 	if conn == nil {
 		return nil
 	}
 
+	maxTries := cfg.DataBase.RetryMaxTries
+	if maxTries <= 0 {
+		maxTries = defaultMaxTries
+	}
+
 	return &PgxRetry{
-		dbpool: conn,
-		log:    log,
+		dbpool:          conn,
+		breaker:         newBreaker(cfg.DataBase.BreakerThreshold, durationOrDefault(cfg.DataBase.BreakerCooldown, time.Second, defaultBreakerCooldown)),
+		maxTries:        uint(maxTries),
+		initialInterval: durationOrDefault(cfg.DataBase.RetryInitialInterval, time.Millisecond, defaultInitialInterval),
+		maxInterval:     durationOrDefault(cfg.DataBase.RetryMaxInterval, time.Millisecond, defaultMaxInterval),
+		log:             log,
 	}
 }
 
 func (pgr *PgxRetry) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
-	start := time.Now()
-	operation := func() (pgconn.CommandTag, error) {
-		tag, err := pgr.dbpool.Exec(ctx, sql, arguments...)
+	ctx, span := startSpan(ctx, "Exec", sql)
+	defer span.End()
 
-		if err != nil {
-			end := time.Now()
-			duration := end.Sub(start)
-			var connectErr *pgconn.ConnectError
-			if errors.As(err, &connectErr) {
-				log.Debugf("ошибка подключения к базе; Пробуем еще раз, прошло времени: %v сек", duration.Seconds())
-
-				return tag, backoff.RetryAfter(retryInterval)
-			}
+	tag, err := retry(ctx, pgr, isRetryable, func() (pgconn.CommandTag, error) {
+		return pgr.dbpool.Exec(ctx, sql, arguments...)
+	})
+	tracing.RecordError(span, err)
 
-			log.Debugf("ошибка фатальна; Прошло времени: %v сек", duration.Seconds())
-			return tag, backoff.Permanent(err)
-		}
-
-		return tag, nil
-	}
-
-	return backoff.Retry(ctx, operation, backoff.WithBackOff(pgr.getBackOffOptions()), backoff.WithMaxTries(maxRetries))
+	return tag, err
 }
 
+// QueryRow откладывает запрос до Scan, потому что только там pgx возвращает ошибку и его можно повторить.
 func (pgr *PgxRetry) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-	row := pgr.dbpool.QueryRow(ctx, sql, args...)
-
-	return row
+	return &retryRow{pgr: pgr, ctx: ctx, sql: sql, args: args}
 }
 
+// Query повторяет только отправку запроса; ошибки во время чтения строк возвращаются вызывающему как есть.
 func (pgr *PgxRetry) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
-	rows, err := pgr.dbpool.Query(ctx, sql, args...)
+	ctx, span := startSpan(ctx, "Query", sql)
+	defer span.End()
+
+	rows, err := retry(ctx, pgr, isRetryable, func() (pgx.Rows, error) {
+		rows, err := pgr.dbpool.Query(ctx, sql, args...)
+		if err != nil {
+			return nil, err
+		}
+		// pgx откладывает ошибку выполнения до первого Next, поэтому ошибки соединения проверяем сразу
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		return rows, nil
+	})
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, fmt.Errorf("ошибка при выполнении SQL: %w", err)
 	}
 
@@ -80,14 +103,73 @@ func (pgr *PgxRetry) Query(ctx context.Context, sql string, args ...interface{})
 }
 
 func (pgr *PgxRetry) Begin(ctx context.Context) (pgx.Tx, error) {
-	tx, err := pgr.dbpool.Begin(ctx)
+	ctx, span := startSpan(ctx, "Begin", "")
+	defer span.End()
+
+	tx, err := retry(ctx, pgr, isRetryable, func() (pgx.Tx, error) {
+		return pgr.dbpool.Begin(ctx)
+	})
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
 	}
 
 	return tx, nil
 }
 
+// RunInTx выполняет fn в транзакции и повторяет ее целиком при обрыве соединения,
+// serialization_failure и deadlock_detected. fn должна быть готова к повторному вызову.
+func (pgr *PgxRetry) RunInTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	ctx, span := startSpan(ctx, "RunInTx", "")
+	defer span.End()
+
+	_, err := retry(ctx, pgr, isRetryableTx, func() (struct{}, error) {
+		tx, err := pgr.dbpool.Begin(ctx)
+		if err != nil {
+			return struct{}{}, fmt.Errorf("ошибка начала транзакции: %w", err)
+		}
+
+		if err := fn(tx); err != nil {
+			if rollbackErr := tx.Rollback(context.WithoutCancel(ctx)); rollbackErr != nil {
+				pgr.log.Errorf("ошибка отката транзакции: %v", rollbackErr)
+			}
+
+			return struct{}{}, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			err = fmt.Errorf("ошибка при коммите транзакции: %w", err)
+			// при обрыве связи во время коммита результат неизвестен, повторять транзакцию нельзя
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) {
+				return struct{}{}, backoff.Permanent(err)
+			}
+
+			return struct{}{}, err
+		}
+
+		return struct{}{}, nil
+	})
+	tracing.RecordError(span, err)
+
+	return err
+}
+
+func (pgr *PgxRetry) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	ctx, span := startSpan(ctx, "Acquire", "")
+	defer span.End()
+
+	conn, err := retry(ctx, pgr, isRetryable, func() (*pgxpool.Conn, error) {
+		return pgr.dbpool.Acquire(ctx)
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("ошибка получения соединения: %w", err)
+	}
+
+	return conn, nil
+}
+
 func (pgr *PgxRetry) Ping(ctx context.Context) error {
 	err := pgr.dbpool.Ping(ctx)
 	if err != nil {
@@ -97,11 +179,83 @@ func (pgr *PgxRetry) Ping(ctx context.Context) error {
 	return nil
 }
 
+func (pgr *PgxRetry) Stat() *pgxpool.Stat {
+	return pgr.dbpool.Stat()
+}
+
 func (pgr *PgxRetry) getBackOffOptions() *backoff.ExponentialBackOff {
 	return &backoff.ExponentialBackOff{
-		InitialInterval:     retryInterval * time.Second,
-		RandomizationFactor: randomizationFactor,
+		InitialInterval:     pgr.initialInterval,
+		RandomizationFactor: backoff.DefaultRandomizationFactor,
 		Multiplier:          multiplier,
-		MaxInterval:         maxBackoffInterval,
+		MaxInterval:         pgr.maxInterval,
 	}
 }
+
+func retry[T any](ctx context.Context, pgr *PgxRetry, retryable func(error) bool, operation func() (T, error)) (T, error) {
+	start := time.Now()
+	return backoff.Retry(ctx, func() (T, error) {
+		var zero T
+		if err := pgr.breaker.allow(); err != nil {
+			return zero, backoff.Permanent(err)
+		}
+
+		res, err := operation()
+		if err == nil {
+			pgr.breaker.success()
+			return res, nil
+		}
+
+		if isConnectionError(err) {
+			pgr.breaker.failure()
+		} else {
+			pgr.breaker.success()
+		}
+		if ctx.Err() != nil || !retryable(err) {
+			return res, backoff.Permanent(err)
+		}
+
+		pgr.log.Debugf("повторяемая ошибка базы; Пробуем еще раз, прошло времени: %v сек: %v", time.Since(start).Seconds(), err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(attribute.String("error", err.Error())))
+		return res, err
+	}, backoff.WithBackOff(pgr.getBackOffOptions()), backoff.WithMaxTries(pgr.maxTries))
+}
+
+type retryRow struct {
+	pgr  *PgxRetry
+	ctx  context.Context
+	sql  string
+	args []interface{}
+}
+
+func (r *retryRow) Scan(dest ...any) error {
+	ctx, span := startSpan(r.ctx, "QueryRow", r.sql)
+	defer span.End()
+
+	_, err := retry(ctx, r.pgr, isRetryable, func() (struct{}, error) {
+		return struct{}{}, r.pgr.dbpool.QueryRow(ctx, r.sql, r.args...).Scan(dest...)
+	})
+	// отсутствие строки - штатный результат, а не ошибка запроса
+	if !errors.Is(err, pgx.ErrNoRows) {
+		tracing.RecordError(span, err)
+	}
+
+	return err
+}
+
+func startSpan(ctx context.Context, operation string, sql string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation)}
+	if sql != "" {
+		attributes = append(attributes, semconv.DBQueryText(sql))
+	}
+
+	return tracer.Start(ctx, "db."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+func durationOrDefault(value int, unit time.Duration, def time.Duration) time.Duration {
+	if value <= 0 {
+		return def
+	}
+
+	return time.Duration(value) * unit
+}
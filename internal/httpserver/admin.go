@@ -0,0 +1,52 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/metrics"
+)
+
+// AdminServer обслуживает служебные эндпоинты на отдельном адресе, который не публикуется наружу.
+type AdminServer struct {
+	Master *echo.Echo
+}
+
+func NewAdminServer(cfg *config.Config, log *zap.SugaredLogger, lc fx.Lifecycle, shutdowner fx.Shutdowner, m *metrics.Metrics) *AdminServer {
+	adminServer := echo.New()
+	adminServer.HideBanner = true
+	adminServer.HidePort = true
+	adminServer.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})))
+
+	if cfg.HTTPServer.AdminBindAddress == "" {
+		log.Infof("admin HTTP server disabled")
+
+		return &AdminServer{Master: adminServer}
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			log.Infof("starting admin HTTP server. Bind: %s", cfg.HTTPServer.AdminBindAddress)
+			go func() {
+				if err := adminServer.Start(cfg.HTTPServer.AdminBindAddress); err != nil && err != http.ErrServerClosed {
+					log.Errorf("failed to start admin HTTP Server: %v", err)
+					_ = shutdowner.Shutdown()
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return adminServer.Shutdown(ctx)
+		},
+	})
+
+	return &AdminServer{
+		Master: adminServer,
+	}
+}
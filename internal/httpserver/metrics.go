@@ -0,0 +1,37 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dontagr/loyalty/internal/metrics"
+)
+
+// requestMetrics считает запросы по шаблону маршрута, а не по URI, иначе номера заказов раздуют число рядов.
+func requestMetrics(m *metrics.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			code := c.Response().Status
+			if err != nil {
+				code = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					code = httpErr.Code
+				}
+			}
+			m.ObserveHTTPRequest(c.Request().Method, route, code, time.Since(start))
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package httpserver
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/dontagr/loyalty/internal/service/audit"
+)
+
+// requestInfo передает адрес клиента и X-Request-ID в контекст запроса для записей журнала аудита.
+// Должен стоять после middleware.RequestID, который выставляет заголовок ответа.
+func requestInfo() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := audit.WithRequestInfo(c.Request().Context(), audit.RequestInfo{
+				ClientIP:  c.RealIP(),
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+			})
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
@@ -7,11 +7,14 @@ import (
 	"github.com/dontagr/loyalty/internal/service/checkup"
 	"github.com/dontagr/loyalty/internal/service/handler"
 	"github.com/dontagr/loyalty/internal/service/jwt"
+	"github.com/dontagr/loyalty/internal/service/partner"
 )
 
 func InitRouting(
 	server *httpserver.HTTPServer,
+	adminServer *httpserver.AdminServer,
 	jwt *jwt.JWTService,
+	partner *partner.Service,
 	handler *handler.Handler,
 ) error {
 	var err error
@@ -21,14 +24,41 @@ func InitRouting(
 		return fmt.Errorf("failed create validator %v", err)
 	}
 
+	server.Master.GET("/.well-known/jwks.json", handler.GetJWKS)
+
 	g := server.Master.Group("/api/user")
 	g.POST("/register", handler.SignUp)
 	g.POST("/login", handler.SignIn)
+	g.POST("/token/refresh", handler.RefreshToken)
+	g.POST("/logout", handler.Logout, jwt.GetMiddleware(jwtConfig))
 	g.GET("/orders", handler.GetOrder, jwt.GetMiddleware(jwtConfig))
 	g.POST("/orders", handler.CreateOrder, jwt.GetMiddleware(jwtConfig))
 	g.GET("/withdrawals", handler.GetWithdraw, jwt.GetMiddleware(jwtConfig))
 	g.GET("/balance", handler.GetBalance, jwt.GetMiddleware(jwtConfig))
 	g.POST("/balance/withdraw", handler.PostBalanceWithdraw, jwt.GetMiddleware(jwtConfig))
+	g.POST("/balance/transfer", handler.PostBalanceTransfer, jwt.GetMiddleware(jwtConfig))
+	g.GET("/ledger", handler.GetLedger, jwt.GetMiddleware(jwtConfig))
+
+	admin := server.Master.Group("/api/admin", jwt.GetMiddleware(jwtConfig), jwt.GetAdminMiddleware())
+	admin.GET("/reconciliation", handler.GetReconciliation)
+	admin.POST("/reconciliation", handler.RunReconciliation)
+	admin.GET("/users/:login", handler.GetAdminUser)
+	admin.GET("/users/:login/orders", handler.GetAdminUserOrders)
+	admin.GET("/users/:login/withdrawals", handler.GetAdminUserWithdrawals)
+	admin.POST("/users/:login/block", handler.BlockUser)
+	admin.DELETE("/users/:login/block", handler.UnblockUser)
+	admin.POST("/users/:login/adjustments", handler.AdjustBalance)
+	admin.POST("/orders/:number/reset", handler.ResetOrder)
+	admin.POST("/orders/:number/invalidate", handler.InvalidateOrder)
+	admin.GET("/audit", handler.GetAuditLog)
+	admin.POST("/withdrawals/:order/reverse", handler.ReverseWithdrawal)
+
+	partnerGroup := server.Master.Group("/api/partner", partner.GetMiddleware())
+	partnerGroup.POST("/withdrawals/:order/complete", handler.CompletePartnerWithdrawal)
+	partnerGroup.POST("/withdrawals/:order/reverse", handler.ReversePartnerWithdrawal)
+
+	adminServer.Master.GET("/healthz", handler.Healthz)
+	adminServer.Master.GET("/readyz", handler.Readyz)
 
 	return nil
 }
@@ -2,22 +2,38 @@ package httpserver
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
 	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/metrics"
+	"github.com/dontagr/loyalty/internal/tracing"
 )
 
 type HTTPServer struct {
 	Master *echo.Echo
 }
 
-func NewServer(cfg *config.Config, log *zap.SugaredLogger, lc fx.Lifecycle, shutdowner fx.Shutdowner) *HTTPServer {
+func NewServer(cfg *config.Config, log *zap.SugaredLogger, lc fx.Lifecycle, shutdowner fx.Shutdowner, m *metrics.Metrics) (*HTTPServer, error) {
+	ipExtractor, err := newIPExtractor(cfg.HTTPServer.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
 	mainServer := echo.New()
+	mainServer.IPExtractor = ipExtractor
+
+	mainServer.Use(otelecho.Middleware(tracing.ServiceName(cfg.Tracing)))
+	mainServer.Use(middleware.RequestID())
+	mainServer.Use(requestInfo())
 
 	mainServer.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
 		LogURI:          true,
@@ -26,20 +42,24 @@ func NewServer(cfg *config.Config, log *zap.SugaredLogger, lc fx.Lifecycle, shut
 		LogError:        true,
 		LogResponseSize: true,
 		LogLatency:      true,
+		LogRequestID:    true,
 		HandleError:     true,
 		LogHeaders:      []string{echo.HeaderContentType, echo.HeaderContentEncoding, echo.HeaderAcceptEncoding},
-		LogValuesFunc: func(_ echo.Context, v middleware.RequestLoggerValues) error {
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			traceID := trace.SpanContextFromContext(c.Request().Context()).TraceID().String()
 			if v.Error == nil {
-				log.Infow("Request", "Method", v.Method, "URI", v.URI, "Status", v.Status, "Duration", v.Latency, "ResponseSize", v.ResponseSize, "Headers", v.Headers)
+				log.Infow("Request", "RequestID", v.RequestID, "Method", v.Method, "URI", v.URI, "Status", v.Status, "Duration", v.Latency, "ResponseSize", v.ResponseSize, "Headers", v.Headers, "TraceID", traceID)
 			} else {
-				log.Errorw(v.Error.Error(), "Method", v.Method, "URI", v.URI, "Status", v.Status, "Duration", v.Latency, "ResponseSize", v.ResponseSize, "Headers", v.Headers)
+				log.Errorw(v.Error.Error(), "RequestID", v.RequestID, "Method", v.Method, "URI", v.URI, "Status", v.Status, "Duration", v.Latency, "ResponseSize", v.ResponseSize, "Headers", v.Headers, "TraceID", traceID)
 			}
 
 			return nil
 		},
 	}))
+	mainServer.Use(requestMetrics(m))
 	mainServer.Use(middleware.Decompress())
 	mainServer.Use(middleware.Gzip())
+	mainServer.Use(queryTimeout(cfg.HTTPServer))
 
 	lc.Append(fx.Hook{
 		OnStart: func(_ context.Context) error {
@@ -59,5 +79,24 @@ func NewServer(cfg *config.Config, log *zap.SugaredLogger, lc fx.Lifecycle, shut
 
 	return &HTTPServer{
 		Master: mainServer,
+	}, nil
+}
+
+// newIPExtractor доверяет X-Forwarded-For только от перечисленных прокси, иначе адрес клиента
+// можно подделать заголовком и обойти ограничение попыток входа по IP.
+func newIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, cidr := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
 	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
 }
@@ -0,0 +1,32 @@
+package httpserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dontagr/loyalty/internal/config"
+)
+
+// queryTimeout ограничивает время обработки запроса через контекст, который дальше уходит в запросы к базе.
+// Ответ по истечении срока формирует сам обработчик (503), поэтому соединение здесь не обрывается.
+func queryTimeout(cfg config.HTTPServer) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timeout := cfg.QueryTimeout
+			if endpoint, exists := cfg.EndpointTimeouts[c.Request().Method+" "+c.Path()]; exists {
+				timeout = endpoint
+			}
+			if timeout <= 0 {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), time.Duration(timeout)*time.Millisecond)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
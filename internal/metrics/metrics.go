@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+)
+
+const namespace = "gophermart"
+
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	queueDepth      prometheus.Gauge
+	updaterOutcomes *prometheus.CounterVec
+	accrualRequests *prometheus.CounterVec
+	accrualDuration *prometheus.HistogramVec
+	ordersUploaded  prometheus.Counter
+	pointsAccrued   prometheus.Counter
+	pointsWithdrawn prometheus.Counter
+	discrepancies   prometheus.Gauge
+	corrected       prometheus.Counter
+}
+
+func NewMetrics(dbpool *pgretry.PgxRetry) *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Количество HTTP запросов по маршрутам и кодам ответа.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Время обработки HTTP запросов по маршрутам.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "updater",
+			Name:      "queue_depth",
+			Help:      "Количество заказов, ожидающих опроса системы расчета в очереди воркеров.",
+		}),
+		updaterOutcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "updater",
+			Name:      "orders_processed_total",
+			Help:      "Результаты опроса заказов по статусам.",
+		}, []string{"status"}),
+		accrualRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "accrual",
+			Name:      "requests_total",
+			Help:      "Запросы к системе расчета по кодам ответа, 0 - ошибка соединения.",
+		}, []string{"code"}),
+		accrualDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "accrual",
+			Name:      "request_duration_seconds",
+			Help:      "Время ответа системы расчета.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"code"}),
+		ordersUploaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_uploaded_total",
+			Help:      "Количество загруженных пользователями заказов.",
+		}),
+		pointsAccrued: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "points_accrued_total",
+			Help:      "Сумма начисленных баллов.",
+		}),
+		pointsWithdrawn: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "points_withdrawn_total",
+			Help:      "Сумма списанных баллов.",
+		}),
+		discrepancies: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "reconciliation",
+			Name:      "discrepancies",
+			Help:      "Количество расхождений балансов в последней плановой сверке.",
+		}),
+		corrected: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "reconciliation",
+			Name:      "corrected_total",
+			Help:      "Количество балансов, исправленных плановыми сверками.",
+		}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.queueDepth,
+		m.updaterOutcomes,
+		m.accrualRequests,
+		m.accrualDuration,
+		m.ordersUploaded,
+		m.pointsAccrued,
+		m.pointsWithdrawn,
+		m.discrepancies,
+		m.corrected,
+	)
+	if dbpool != nil {
+		m.Registry.MustRegister(newPoolCollector(dbpool))
+	}
+
+	return m
+}
+
+func (m *Metrics) ObserveHTTPRequest(method string, route string, code int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (m *Metrics) SetQueueDepth(depth int) {
+	m.queueDepth.Set(float64(depth))
+}
+
+func (m *Metrics) OrderPolled(status string) {
+	m.updaterOutcomes.WithLabelValues(status).Inc()
+}
+
+func (m *Metrics) ObserveAccrualRequest(code int, duration time.Duration) {
+	label := strconv.Itoa(code)
+	m.accrualRequests.WithLabelValues(label).Inc()
+	m.accrualDuration.WithLabelValues(label).Observe(duration.Seconds())
+}
+
+func (m *Metrics) OrderUploaded() {
+	m.ordersUploaded.Inc()
+}
+
+// PointsAccrued и PointsWithdrawn принимают суммы в копейках, как они хранятся в базе.
+func (m *Metrics) PointsAccrued(amount int) {
+	m.pointsAccrued.Add(float64(amount) / 100)
+}
+
+func (m *Metrics) PointsWithdrawn(amount int) {
+	m.pointsWithdrawn.Add(float64(amount) / 100)
+}
+
+func (m *Metrics) ReconciliationFinished(discrepancies int, corrected int) {
+	m.discrepancies.Set(float64(discrepancies))
+	m.corrected.Add(float64(corrected))
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+)
+
+// poolCollector снимает статистику pgxpool в момент опроса, чтобы не держать отдельный цикл обновления.
+type poolCollector struct {
+	dbpool *pgretry.PgxRetry
+
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	totalConns       *prometheus.Desc
+	maxConns         *prometheus.Desc
+	acquireCount     *prometheus.Desc
+	acquireDuration  *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+}
+
+func newPoolCollector(dbpool *pgretry.PgxRetry) *poolCollector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		dbpool:           dbpool,
+		acquiredConns:    desc("acquired_conns", "Соединения, занятые в данный момент."),
+		idleConns:        desc("idle_conns", "Свободные соединения."),
+		totalConns:       desc("total_conns", "Всего открытых соединений."),
+		maxConns:         desc("max_conns", "Максимальный размер пула."),
+		acquireCount:     desc("acquire_total", "Количество успешных получений соединения."),
+		acquireDuration:  desc("acquire_duration_seconds_total", "Суммарное время ожидания соединения."),
+		emptyAcquires:    desc("empty_acquire_total", "Получения соединения, которым пришлось ждать освобождения."),
+		canceledAcquires: desc("canceled_acquire_total", "Получения соединения, отмененные контекстом."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquires
+	ch <- c.canceledAcquires
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.dbpool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/service/audit"
+	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/service/jwt"
+	"github.com/dontagr/loyalty/internal/service/models"
+	storeModels "github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	ActionUserView          = "admin.user.view"
+	ActionUserOrdersView    = "admin.user.orders.view"
+	ActionUserWithdrawsView = "admin.user.withdrawals.view"
+	ActionUserBlock         = "admin.user.block"
+	ActionUserUnblock       = "admin.user.unblock"
+	ActionBalanceAdjust     = "admin.balance.adjust"
+	ActionOrderReset        = "admin.order.reset"
+	ActionOrderInvalidate   = "admin.order.invalidate"
+)
+
+type Service struct {
+	users       interfaces.UserStore
+	orders      interfaces.OrderStore
+	withdrawals interfaces.WithdrawalStore
+	ledger      interfaces.LedgerStore
+	audit       *audit.Service
+	jwt         *jwt.JWTService
+	log         *zap.SugaredLogger
+}
+
+func NewAdminService(
+	users interfaces.UserStore,
+	orders interfaces.OrderStore,
+	withdrawals interfaces.WithdrawalStore,
+	ledger interfaces.LedgerStore,
+	auditService *audit.Service,
+	jwtService *jwt.JWTService,
+	log *zap.SugaredLogger,
+) *Service {
+	return &Service{
+		users:       users,
+		orders:      orders,
+		withdrawals: withdrawals,
+		ledger:      ledger,
+		audit:       auditService,
+		jwt:         jwtService,
+		log:         log,
+	}
+}
+
+func (a *Service) GetUser(ctx context.Context, actor *storeModels.User, login string) (*models.ResponseAdminUser, *customerror.CustomError) {
+	user, cError := a.findUser(ctx, login)
+	if cError != nil {
+		return nil, cError
+	}
+	a.record(ctx, actor, ActionUserView, login, nil)
+
+	return &models.ResponseAdminUser{
+		ID:        user.ID,
+		Login:     user.Login,
+		Role:      user.Role,
+		Balance:   float64(user.Balance) / 100,
+		BlockedAt: user.BlockedAt,
+	}, nil
+}
+
+func (a *Service) GetUserOrders(ctx context.Context, actor *storeModels.User, login string) ([]*storeModels.Order, *customerror.CustomError) {
+	user, cError := a.findUser(ctx, login)
+	if cError != nil {
+		return nil, cError
+	}
+
+	list, err := a.orders.GetListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get list order: %w", err))
+	}
+	a.record(ctx, actor, ActionUserOrdersView, login, nil)
+
+	return list, nil
+}
+
+func (a *Service) GetUserWithdrawals(ctx context.Context, actor *storeModels.User, login string) ([]*storeModels.Withdrawal, *customerror.CustomError) {
+	user, cError := a.findUser(ctx, login)
+	if cError != nil {
+		return nil, cError
+	}
+
+	list, err := a.withdrawals.GetWithdrawalListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get list withdrawal: %w", err))
+	}
+	a.record(ctx, actor, ActionUserWithdrawsView, login, nil)
+
+	return list, nil
+}
+
+func (a *Service) BlockUser(ctx context.Context, actor *storeModels.User, login string, reason string) *customerror.CustomError {
+	user, cError := a.findUser(ctx, login)
+	if cError != nil {
+		return cError
+	}
+	if user.ID == actor.ID {
+		return customerror.NewCustomError(customerror.Conflict, "Нельзя заблокировать самого себя", nil)
+	}
+
+	if err := a.users.Block(ctx, user.ID, reason); err != nil {
+		return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
+	}
+	if err := a.jwt.RevokeUser(ctx, user.ID); err != nil {
+		return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed revoke sessions: %w", err))
+	}
+	a.record(ctx, actor, ActionUserBlock, login, map[string]any{"reason": reason})
+
+	return nil
+}
+
+func (a *Service) UnblockUser(ctx context.Context, actor *storeModels.User, login string, reason string) *customerror.CustomError {
+	user, cError := a.findUser(ctx, login)
+	if cError != nil {
+		return cError
+	}
+
+	if err := a.users.Unblock(ctx, user.ID); err != nil {
+		return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
+	}
+	a.record(ctx, actor, ActionUserUnblock, login, reasonDetails(reason))
+
+	return nil
+}
+
+func (a *Service) AdjustBalance(ctx context.Context, actor *storeModels.User, login string, request *models.RequestBalanceAdjustment) (*storeModels.LedgerEntry, *customerror.CustomError) {
+	amount := int(math.Round(request.Amount * 100))
+	if amount == 0 {
+		return nil, customerror.NewCustomError(customerror.BadRequest, "Неверный формат запроса", nil)
+	}
+
+	user, cError := a.findUser(ctx, login)
+	if cError != nil {
+		return nil, cError
+	}
+
+	entry := &storeModels.LedgerEntry{
+		UserID:        user.ID,
+		EntryType:     storeModels.LedgerAdjustment,
+		ContraAccount: storeModels.ContraAdjustment,
+		Amount:        amount,
+		Reason:        &request.Reason,
+	}
+	err := a.ledger.Adjust(ctx, entry, func(tx pgx.Tx) error {
+		return a.audit.RecordTx(ctx, tx, &storeModels.AuditEntry{
+			ActorID: &actor.ID,
+			Action:  ActionBalanceAdjust,
+			Target:  login,
+			Amount:  &amount,
+			Details: a.details(ActionBalanceAdjust, map[string]any{"reason": request.Reason, "ledger_id": entry.ID}),
+		})
+	})
+	if errors.Is(err, storeModels.ErrInsufficientBalance) {
+		return nil, customerror.NewCustomError(customerror.Payment, "На счету недостаточно средств", nil)
+	}
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed adjust balance: %w", err))
+	}
+
+	return entry, nil
+}
+
+func (a *Service) ResetOrder(ctx context.Context, actor *storeModels.User, orderID string, reason string) *customerror.CustomError {
+	order, cError := a.findOrder(ctx, orderID)
+	if cError != nil {
+		return cError
+	}
+
+	reset, err := a.orders.ResetForPolling(ctx, order.ID)
+	if err != nil {
+		return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
+	}
+	if !reset {
+		return customerror.NewCustomError(customerror.Conflict, "Обработанный заказ нельзя отправить на повторный опрос", nil)
+	}
+	a.record(ctx, actor, ActionOrderReset, orderID, reasonDetails(reason))
+
+	return nil
+}
+
+func (a *Service) InvalidateOrder(ctx context.Context, actor *storeModels.User, orderID string, reason string) *customerror.CustomError {
+	order, cError := a.findOrder(ctx, orderID)
+	if cError != nil {
+		return cError
+	}
+	if order.Status == storeModels.StatusProcessed {
+		return customerror.NewCustomError(customerror.Conflict, "Обработанный заказ нельзя отменить", nil)
+	}
+
+	if order.Status != storeModels.StatusInvalid {
+		_, err := a.orders.UpdateOrder(ctx, &storeModels.Order{ID: order.ID, Status: storeModels.StatusInvalid}, nil)
+		if err != nil {
+			return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
+		}
+	}
+	a.record(ctx, actor, ActionOrderInvalidate, orderID, reasonDetails(reason))
+
+	return nil
+}
+
+func (a *Service) GetAuditLog(ctx context.Context, request *models.RequestAuditList) (*models.ResponseAuditList, *customerror.CustomError) {
+	return a.audit.GetList(ctx, request)
+}
+
+func (a *Service) findUser(ctx context.Context, login string) (*storeModels.User, *customerror.CustomError) {
+	user, err := a.users.GetUser(ctx, login)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get user: %w", err))
+	}
+	if user.Login == "" {
+		return nil, customerror.NewCustomError(customerror.NotFound, "Пользователь не найден", nil)
+	}
+
+	return user, nil
+}
+
+func (a *Service) findOrder(ctx context.Context, orderID string) (*storeModels.Order, *customerror.CustomError) {
+	order, err := a.orders.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get order: %w", err))
+	}
+	if order.ID == "" {
+		return nil, customerror.NewCustomError(customerror.NotFound, "Заказ не найден", nil)
+	}
+
+	return order, nil
+}
+
+func (a *Service) record(ctx context.Context, actor *storeModels.User, action string, target string, details map[string]any) {
+	a.audit.Record(ctx, &storeModels.AuditEntry{ActorID: &actor.ID, Action: action, Target: target, Details: a.details(action, details)})
+}
+
+func (a *Service) details(action string, details map[string]any) json.RawMessage {
+	if details == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(details)
+	if err != nil {
+		a.log.Errorf("failed marshal audit details for %s: %v", action, err)
+		return nil
+	}
+
+	return payload
+}
+
+func reasonDetails(reason string) map[string]any {
+	if reason == "" {
+		return nil
+	}
+
+	return map[string]any{"reason": reason}
+}
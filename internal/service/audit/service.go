@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/service/models"
+	"github.com/dontagr/loyalty/internal/service/pagination"
+	storeModels "github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	ActionSignUp             = "user.sign_up"
+	ActionLogin              = "user.login"
+	ActionLoginFailed        = "user.login_failed"
+	ActionOrderUpload        = "order.upload"
+	ActionAccrualCredit      = "order.accrual"
+	ActionWithdrawalCreate   = "withdrawal.create"
+	ActionWithdrawalComplete = "withdrawal.complete"
+	ActionWithdrawalReverse  = "withdrawal.reverse"
+	ActionPointsExpire       = "points.expire"
+	ActionTransferSend       = "transfer.send"
+	ActionTransferReceive    = "transfer.receive"
+	ActionTierChange         = "tier.change"
+)
+
+type (
+	requestInfoKey struct{}
+	RequestInfo    struct {
+		ClientIP  string
+		RequestID string
+	}
+)
+
+// WithRequestInfo кладет в контекст адрес клиента и идентификатор запроса, чтобы сервисам
+// не приходилось передавать их явно в каждый вызов.
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+func requestInfoFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+
+	return info
+}
+
+type Service struct {
+	store interfaces.AuditStore
+	log   *zap.SugaredLogger
+}
+
+func NewAuditService(store interfaces.AuditStore, log *zap.SugaredLogger) *Service {
+	return &Service{store: store, log: log}
+}
+
+// Record дописывает в запись данные запроса и сохраняет ее. Действие к этому моменту уже выполнено,
+// поэтому ошибка записи только логируется, а отмена запроса клиентом не мешает записи.
+func (a *Service) Record(ctx context.Context, entry *storeModels.AuditEntry) {
+	info := requestInfoFromContext(ctx)
+	entry.ClientIP = info.ClientIP
+	entry.RequestID = info.RequestID
+
+	if err := a.store.Add(context.WithoutCancel(ctx), entry); err != nil {
+		a.log.Errorw("failed write audit entry", "action", entry.Action, "target", entry.Target, "request_id", entry.RequestID, "error", err)
+	}
+}
+
+// RecordTx сохраняет запись в транзакции действия. Ошибка записи откатывает действие,
+// поэтому движения баллов не остаются без следа в журнале.
+func (a *Service) RecordTx(ctx context.Context, tx pgx.Tx, entry *storeModels.AuditEntry) error {
+	info := requestInfoFromContext(ctx)
+	entry.ClientIP = info.ClientIP
+	entry.RequestID = info.RequestID
+
+	return a.store.AddTx(ctx, tx, entry)
+}
+
+func (a *Service) GetList(ctx context.Context, request *models.RequestAuditList) (*models.ResponseAuditList, *customerror.CustomError) {
+	filter, err := a.buildFilter(request)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.BadRequest, "Неверный формат запроса", err)
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	list, err := a.store.GetList(ctx, filter)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get audit log: %w", err))
+	}
+
+	response := &models.ResponseAuditList{Entries: list}
+	if len(list) > limit {
+		response.Entries = list[:limit]
+		last := response.Entries[limit-1]
+		response.NextCursor = pagination.EncodeCursor(pagination.Cursor{ID: strconv.FormatInt(last.ID, 10)})
+	}
+
+	return response, nil
+}
+
+func (a *Service) buildFilter(request *models.RequestAuditList) (*storeModels.AuditFilter, error) {
+	var err error
+	filter := &storeModels.AuditFilter{Action: request.Action, Target: request.Target}
+
+	filter.Limit, err = pagination.ParseLimit(request.Limit)
+	if err != nil {
+		return nil, err
+	}
+	filter.From, err = pagination.ParseTime(request.From)
+	if err != nil {
+		return nil, err
+	}
+	filter.To, err = pagination.ParseTime(request.To)
+	if err != nil {
+		return nil, err
+	}
+	if request.ActorID != "" {
+		actorID, err := strconv.Atoi(request.ActorID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid actor_id: %w", err)
+		}
+		filter.ActorID = &actorID
+	}
+
+	cursor, err := pagination.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		filter.BeforeID, err = strconv.ParseInt(cursor.ID, 10, 64)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+	}
+
+	return filter, nil
+}
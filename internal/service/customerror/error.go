@@ -1,6 +1,10 @@
 package customerror
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 type (
 	CustomError struct {
@@ -16,6 +20,10 @@ const (
 	Payment
 	Unauthorized
 	Conflict
+	BadRequest
+	Unavailable
+	Forbidden
+	NotFound
 )
 
 func (e *CustomError) Error() string {
@@ -26,6 +34,11 @@ func (e *CustomError) Error() string {
 }
 
 func NewCustomError(code int, message string, err error) *CustomError {
+	if errors.Is(err, context.DeadlineExceeded) {
+		code = Unavailable
+		message = "Сервис временно недоступен"
+	}
+
 	return &CustomError{
 		Code:    code,
 		Message: message,
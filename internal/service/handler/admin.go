@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/models"
+	withdrawalService "github.com/dontagr/loyalty/internal/service/withdrawal"
+)
+
+func (h *Handler) GetReconciliation(c echo.Context) error {
+	runs, err := h.rService.GetReport(c.Request().Context())
+	if err != nil {
+		h.log.Errorf("get reconciliation report failed: %v", err)
+		return h.internalError(err)
+	}
+
+	if len(runs) == 0 {
+		return c.NoContent(http.StatusNoContent) // Нет данных для ответа
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
+
+func (h *Handler) RunReconciliation(c echo.Context) error {
+	autoCorrect := false
+	if fix := c.QueryParam("fix"); fix != "" {
+		var err error
+		autoCorrect, err = strconv.ParseBool(fix)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+		}
+	}
+
+	run, err := h.rService.Run(c.Request().Context(), autoCorrect)
+	if err != nil {
+		h.log.Errorf("reconciliation failed: %v", err)
+		return h.internalError(err)
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+func (h *Handler) GetAdminUser(c echo.Context) error {
+	user, intErr := h.aService.GetUser(c.Request().Context(), h.jwt.GetUser(c), c.Param("login"))
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+func (h *Handler) GetAdminUserOrders(c echo.Context) error {
+	list, intErr := h.aService.GetUserOrders(c.Request().Context(), h.jwt.GetUser(c), c.Param("login"))
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+	if len(list) == 0 {
+		return c.NoContent(http.StatusNoContent) // Нет данных для ответа
+	}
+
+	return c.JSON(http.StatusOK, list)
+}
+
+func (h *Handler) GetAdminUserWithdrawals(c echo.Context) error {
+	list, intErr := h.aService.GetUserWithdrawals(c.Request().Context(), h.jwt.GetUser(c), c.Param("login"))
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+	if len(list) == 0 {
+		return c.NoContent(http.StatusNoContent) // Нет данных для ответа
+	}
+
+	return c.JSON(http.StatusOK, list)
+}
+
+func (h *Handler) BlockUser(c echo.Context) error {
+	request := &models.RequestBlockUser{}
+	if echoError := h.bindAdminRequest(c, request); echoError != nil {
+		return echoError
+	}
+
+	intErr := h.aService.BlockUser(c.Request().Context(), h.jwt.GetUser(c), c.Param("login"), request.Reason)
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+
+	return c.JSON(http.StatusOK, "Пользователь заблокирован")
+}
+
+func (h *Handler) UnblockUser(c echo.Context) error {
+	request := &models.RequestAdminReason{}
+	if echoError := h.bindAdminRequest(c, request); echoError != nil {
+		return echoError
+	}
+
+	intErr := h.aService.UnblockUser(c.Request().Context(), h.jwt.GetUser(c), c.Param("login"), request.Reason)
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+
+	return c.JSON(http.StatusOK, "Пользователь разблокирован")
+}
+
+func (h *Handler) AdjustBalance(c echo.Context) error {
+	request := &models.RequestBalanceAdjustment{}
+	if echoError := h.bindAdminRequest(c, request); echoError != nil {
+		return echoError
+	}
+
+	entry, intErr := h.aService.AdjustBalance(c.Request().Context(), h.jwt.GetUser(c), c.Param("login"), request)
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+
+	return c.JSON(http.StatusOK, entry)
+}
+
+func (h *Handler) ResetOrder(c echo.Context) error {
+	request := &models.RequestAdminReason{}
+	if echoError := h.bindAdminRequest(c, request); echoError != nil {
+		return echoError
+	}
+
+	intErr := h.aService.ResetOrder(c.Request().Context(), h.jwt.GetUser(c), c.Param("number"), request.Reason)
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+
+	return c.JSON(http.StatusOK, "Заказ отправлен на повторный опрос")
+}
+
+func (h *Handler) InvalidateOrder(c echo.Context) error {
+	request := &models.RequestAdminReason{}
+	if echoError := h.bindAdminRequest(c, request); echoError != nil {
+		return echoError
+	}
+
+	intErr := h.aService.InvalidateOrder(c.Request().Context(), h.jwt.GetUser(c), c.Param("number"), request.Reason)
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+
+	return c.JSON(http.StatusOK, "Заказ помечен как INVALID")
+}
+
+func (h *Handler) ReverseWithdrawal(c echo.Context) error {
+	request := &models.RequestAdminReason{}
+	if echoError := h.bindAdminRequest(c, request); echoError != nil {
+		return echoError
+	}
+
+	actor := h.jwt.GetUser(c)
+	withdrawal, intErr := h.wService.ReverseWithdraw(c.Request().Context(), c.Param("order"), request.Reason, withdrawalService.SourceAdmin, &actor.ID)
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+
+	return c.JSON(http.StatusOK, withdrawal)
+}
+
+func (h *Handler) GetAuditLog(c echo.Context) error {
+	request := &models.RequestAuditList{}
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, request); err != nil {
+		h.log.Errorf("request failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+
+	response, intErr := h.aService.GetAuditLog(c.Request().Context(), request)
+	if intErr != nil {
+		return h.adminError(intErr)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) bindAdminRequest(c echo.Context, request any) *echo.HTTPError {
+	if err := c.Bind(request); err != nil {
+		h.log.Errorf("request failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+	if err := c.Validate(request); err != nil {
+		h.log.Errorf("validation failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+
+	return nil
+}
+
+func (h *Handler) adminError(intErr *customerror.CustomError) *echo.HTTPError {
+	if intErr.Err != nil {
+		h.log.Errorf(intErr.Error())
+	}
+
+	return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
+}
@@ -11,6 +11,12 @@ import (
 	storeModel "github.com/dontagr/loyalty/internal/store/models"
 )
 
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
 func (h *Handler) GetBalance(c echo.Context) error {
 	jwtUser := h.jwt.GetUser(c)
 	var waitGroup sync.WaitGroup
@@ -18,7 +24,7 @@ func (h *Handler) GetBalance(c echo.Context) error {
 	var userErr error
 	waitGroup.Add(1)
 	go func() {
-		user, userErr = h.uService.GetUser(jwtUser.Login)
+		user, userErr = h.uService.GetUser(c.Request().Context(), jwtUser.Login)
 		waitGroup.Done()
 	}()
 
@@ -26,23 +32,51 @@ func (h *Handler) GetBalance(c echo.Context) error {
 	var withdrawalErr error
 	waitGroup.Add(1)
 	go func() {
-		withdrawal, withdrawalErr = h.wService.GetTotalWithdrawal(jwtUser.ID)
+		withdrawal, withdrawalErr = h.wService.GetTotalWithdrawal(c.Request().Context(), jwtUser.ID)
+		waitGroup.Done()
+	}()
+
+	var expirations []*models.ResponseExpiringPoints
+	var expiringSoon float64
+	var expiringErr error
+	waitGroup.Add(1)
+	go func() {
+		expirations, expiringSoon, expiringErr = h.lService.GetExpiring(c.Request().Context(), jwtUser.ID)
+		waitGroup.Done()
+	}()
+
+	var tier *models.ResponseTier
+	var tierErr error
+	waitGroup.Add(1)
+	go func() {
+		tier, tierErr = h.trService.GetTier(c.Request().Context(), jwtUser.ID)
 		waitGroup.Done()
 	}()
 
 	waitGroup.Wait()
+	if tierErr != nil {
+		h.log.Errorf("get tier failed: %v", tierErr)
+		return h.internalError(tierErr)
+	}
+	if expiringErr != nil {
+		h.log.Errorf("get expiring points failed: %v", expiringErr)
+		return h.internalError(expiringErr)
+	}
 	if withdrawalErr != nil {
 		h.log.Errorf("get total withdrawal failed: %v", withdrawalErr)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка сервера")
+		return h.internalError(withdrawalErr)
 	}
 	if userErr != nil {
 		h.log.Errorf("get user failed: %v", userErr)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка сервера")
+		return h.internalError(userErr)
 	}
 
 	return c.JSON(http.StatusOK, &models.ResponceWithdraw{
-		Balance:    float64(user.Balance) / 100,
-		Withdrawal: withdrawal,
+		Balance:      float64(user.Balance) / 100,
+		Withdrawal:   withdrawal,
+		ExpiringSoon: expiringSoon,
+		Expirations:  expirations,
+		Tier:         tier,
 	})
 }
 
@@ -62,8 +96,41 @@ func (h *Handler) PostBalanceWithdraw(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
 	}
 
+	idempotencyKey := c.Request().Header.Get(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+
 	jwtUser := h.jwt.GetUser(c)
-	intErr := h.wService.SaveWithdraw(requestWithdraw, h.uService, jwtUser.Login)
+	response, intErr := h.wService.SaveWithdraw(c.Request().Context(), requestWithdraw, h.uService, jwtUser.Login, idempotencyKey)
+	if intErr != nil {
+		if intErr.Err != nil {
+			h.log.Infof(intErr.Error())
+		}
+
+		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
+	}
+	if response.Replayed {
+		c.Response().Header().Set(idempotentReplayedHeader, "true")
+	}
+
+	return c.JSONBlob(response.StatusCode, response.Body)
+}
+
+func (h *Handler) PostBalanceTransfer(c echo.Context) error {
+	request := &models.RequestTransfer{}
+	if err := c.Bind(request); err != nil {
+		h.log.Errorf("request failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+	if err := c.Validate(request); err != nil {
+		h.log.Errorf("validation failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+
+	response, intErr := h.tService.Transfer(c.Request().Context(), h.jwt.GetUser(c), request)
 	if intErr != nil {
 		if intErr.Err != nil {
 			h.log.Infof(intErr.Error())
@@ -72,11 +139,15 @@ func (h *Handler) PostBalanceWithdraw(c echo.Context) error {
 		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
 	}
 
-	return c.JSON(http.StatusOK, "Запрос на снятие успешно обработан")
+	return c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) GetWithdraw(c echo.Context) error {
-	list, intErr := h.wService.GetListByUser(h.jwt.GetUser(c))
+	if hasQueryParam(c, "limit", "cursor", "from", "to") {
+		return h.getWithdrawPage(c)
+	}
+
+	list, intErr := h.wService.GetListByUser(c.Request().Context(), h.jwt.GetUser(c))
 	if intErr != nil {
 		if intErr.Err != nil {
 			h.log.Infof(intErr.Error())
@@ -95,3 +166,23 @@ func (h *Handler) GetWithdraw(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, list)
 }
+
+func (h *Handler) getWithdrawPage(c echo.Context) error {
+	request := &models.RequestWithdrawalList{}
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, request); err != nil {
+		h.log.Errorf("request failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+
+	response, intErr := h.wService.GetPageByUser(c.Request().Context(), h.jwt.GetUser(c), request)
+	if intErr != nil {
+		if intErr.Err != nil {
+			h.log.Infof(intErr.Error())
+		}
+
+		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
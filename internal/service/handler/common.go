@@ -1,24 +1,41 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
+	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 
+	"github.com/dontagr/loyalty/internal/service/admin"
 	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/health"
 	"github.com/dontagr/loyalty/internal/service/jwt"
+	"github.com/dontagr/loyalty/internal/service/ledger"
 	"github.com/dontagr/loyalty/internal/service/order"
+	"github.com/dontagr/loyalty/internal/service/ratelimit"
+	"github.com/dontagr/loyalty/internal/service/reconciliation"
+	"github.com/dontagr/loyalty/internal/service/tier"
+	"github.com/dontagr/loyalty/internal/service/transfer"
 	"github.com/dontagr/loyalty/internal/service/user"
 	"github.com/dontagr/loyalty/internal/service/withdrawal"
 )
 
 type (
 	Handler struct {
-		log      *zap.SugaredLogger
-		uService *user.Service
-		oService *order.Service
-		wService *withdrawal.Service
-		jwt      *jwt.JWTService
+		log       *zap.SugaredLogger
+		uService  *user.Service
+		oService  *order.Service
+		wService  *withdrawal.Service
+		lService  *ledger.Service
+		rService  *reconciliation.Service
+		limiter   *ratelimit.LoginLimiter
+		hService  *health.Service
+		aService  *admin.Service
+		tService  *transfer.Service
+		trService *tier.Service
+		jwt       *jwt.JWTService
 	}
 )
 
@@ -26,15 +43,29 @@ func NewHandler(
 	uService *user.Service,
 	oService *order.Service,
 	wService *withdrawal.Service,
+	lService *ledger.Service,
+	rService *reconciliation.Service,
+	limiter *ratelimit.LoginLimiter,
+	hService *health.Service,
+	aService *admin.Service,
+	tService *transfer.Service,
+	trService *tier.Service,
 	log *zap.SugaredLogger,
 	jwtService *jwt.JWTService,
 ) *Handler {
 	h := &Handler{
-		log:      log,
-		uService: uService,
-		oService: oService,
-		wService: wService,
-		jwt:      jwtService,
+		log:       log,
+		uService:  uService,
+		oService:  oService,
+		wService:  wService,
+		lService:  lService,
+		rService:  rService,
+		limiter:   limiter,
+		hService:  hService,
+		aService:  aService,
+		tService:  tService,
+		trService: trService,
+		jwt:       jwtService,
 	}
 
 	return h
@@ -52,7 +83,37 @@ func (h *Handler) convertCustomErrorToServerCode(code int) int {
 		return http.StatusUnauthorized
 	case customerror.Conflict:
 		return http.StatusConflict
+	case customerror.BadRequest:
+		return http.StatusBadRequest
+	case customerror.Unavailable:
+		return http.StatusServiceUnavailable
+	case customerror.Forbidden:
+		return http.StatusForbidden
+	case customerror.NotFound:
+		return http.StatusNotFound
 	default:
 		return 0
 	}
 }
+
+// hasQueryParam сообщает, передан ли хотя бы один из параметров names. Посторонние параметры,
+// например защита от кеширования, не переключают ответ на постраничный формат.
+func hasQueryParam(c echo.Context, names ...string) bool {
+	params := c.QueryParams()
+	for _, name := range names {
+		if _, ok := params[name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// internalError отвечает 503, если запрос не уложился в отведенное время, иначе 500.
+func (h *Handler) internalError(err error) *echo.HTTPError {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Сервис временно недоступен")
+	}
+
+	return echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка сервера")
+}
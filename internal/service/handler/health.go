@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dontagr/loyalty/internal/service/health"
+)
+
+func (h *Handler) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+func (h *Handler) Readyz(c echo.Context) error {
+	report := h.hService.Ready(c.Request().Context())
+	if report.Status != health.StatusOK {
+		h.log.Warnw("readiness check failed", "checks", report.Checks)
+
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) GetJWKS(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+
+	return c.JSON(http.StatusOK, h.jwt.GetJWKS())
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) GetLedger(c echo.Context) error {
+	response, intErr := h.lService.GetListByUser(c.Request().Context(), h.jwt.GetUser(c), c.QueryParam("cursor"), c.QueryParam("limit"))
+	if intErr != nil {
+		if intErr.Err != nil {
+			h.log.Infof(intErr.Error())
+		}
+
+		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
+	}
+
+	if len(response.Entries) == 0 {
+		return c.NoContent(http.StatusNoContent) // Нет данных для ответа
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -28,7 +28,7 @@ func (h *Handler) CreateOrder(c echo.Context) error {
 		return echoErr
 	}
 
-	success, intErr := h.oService.CreateOrder(order.ID, h.jwt.GetUser(c))
+	success, intErr := h.oService.CreateOrder(c.Request().Context(), order.ID, h.jwt.GetUser(c))
 	if intErr != nil {
 		if intErr.Err != nil {
 			h.log.Infof(intErr.Error())
@@ -46,7 +46,11 @@ func (h *Handler) CreateOrder(c echo.Context) error {
 }
 
 func (h *Handler) GetOrder(c echo.Context) error {
-	list, intErr := h.oService.GetListByUser(h.jwt.GetUser(c))
+	if hasQueryParam(c, "limit", "cursor", "status", "from", "to") {
+		return h.getOrderPage(c)
+	}
+
+	list, intErr := h.oService.GetListByUser(c.Request().Context(), h.jwt.GetUser(c))
 	if intErr != nil {
 		if intErr.Err != nil {
 			h.log.Infof(intErr.Error())
@@ -70,6 +74,26 @@ func (h *Handler) GetOrder(c echo.Context) error {
 	return c.JSON(http.StatusOK, list)
 }
 
+func (h *Handler) getOrderPage(c echo.Context) error {
+	request := &models.RequestOrderList{}
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, request); err != nil {
+		h.log.Errorf("request failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+
+	response, intErr := h.oService.GetPageByUser(c.Request().Context(), h.jwt.GetUser(c), request)
+	if intErr != nil {
+		if intErr.Err != nil {
+			h.log.Infof(intErr.Error())
+		}
+
+		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
 func (h *Handler) getOrderBody(c echo.Context) (*models.RequestOrder, *echo.HTTPError) {
 	requestOrder := &models.RequestOrder{}
 	body, err := io.ReadAll(c.Request().Body)
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dontagr/loyalty/internal/service/models"
+	"github.com/dontagr/loyalty/internal/service/withdrawal"
+)
+
+func (h *Handler) CompletePartnerWithdrawal(c echo.Context) error {
+	result, intErr := h.wService.CompleteWithdraw(c.Request().Context(), c.Param("order"))
+	if intErr != nil {
+		if intErr.Err != nil {
+			h.log.Errorf(intErr.Error())
+		}
+
+		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) ReversePartnerWithdrawal(c echo.Context) error {
+	request := &models.RequestPartnerReversal{}
+	if err := c.Bind(request); err != nil {
+		h.log.Errorf("request failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+	if err := c.Validate(request); err != nil {
+		h.log.Errorf("validation failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+
+	result, intErr := h.wService.ReverseWithdraw(c.Request().Context(), c.Param("order"), request.Reason, withdrawal.SourcePartner, nil)
+	if intErr != nil {
+		if intErr.Err != nil {
+			h.log.Errorf(intErr.Error())
+		}
+
+		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -1,37 +1,44 @@
 package handler
 
 import (
+	"context"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/jwt"
 	"github.com/dontagr/loyalty/internal/service/models"
 )
 
+const refreshTokenHeader = "X-Refresh-Token"
+
 func (h *Handler) SignUp(c echo.Context) error {
 	requestUser, echoError := h.getRequestUser(c)
 	if echoError != nil {
 		return echoError
 	}
 
-	hasLogin, err := h.uService.HasLogin(requestUser.Login)
+	hasLogin, err := h.uService.HasLogin(c.Request().Context(), requestUser.Login)
 	if err != nil {
 		h.log.Errorf("has login error: %v", err)
 
-		return echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка сервера")
+		return h.internalError(err)
 	}
 	if hasLogin {
 		return echo.NewHTTPError(http.StatusConflict, "Логин уже занят")
 	}
 
-	jwtHash, err := h.uService.SignUp(requestUser.Login, requestUser.Password)
+	tokens, err := h.uService.SignUp(c.Request().Context(), requestUser.Login, requestUser.Password)
 	if err != nil {
 		h.log.Errorf("failed registration: %v", err)
 
-		return echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка сервера")
+		return h.internalError(err)
 	}
 
-	c.Response().Header().Set("Authorization", jwtHash)
+	h.setTokenHeaders(c, tokens)
 
 	return c.JSON(http.StatusOK, "Пользователь успешно зарегистрирован и аутентифицирован")
 }
@@ -42,30 +49,100 @@ func (h *Handler) SignIn(c echo.Context) error {
 		return echoError
 	}
 
-	user, err := h.uService.GetUser(requestUser.Login)
+	ip := c.RealIP()
+	retryAfter, err := h.limiter.Check(c.Request().Context(), requestUser.Login, ip)
+	if err != nil {
+		h.log.Errorf("login limiter error: %v", err)
+
+		return h.internalError(err)
+	}
+	if retryAfter > 0 {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+		return echo.NewHTTPError(http.StatusTooManyRequests, "Слишком много неудачных попыток входа")
+	}
+
+	user, err := h.uService.GetUser(c.Request().Context(), requestUser.Login)
 	if err != nil {
 		h.log.Errorf("get user error: %v", err)
 
-		return echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка сервера")
+		return h.internalError(err)
 	}
 	if user.Login == "" {
+		h.loginFailed(c.Request().Context(), requestUser.Login, ip)
+
 		return echo.NewHTTPError(http.StatusUnauthorized, "Неверная пара логин/пароль")
 	}
 
-	jwtHash, intErr := h.uService.SignIn(requestUser.Password, user)
+	tokens, intErr := h.uService.SignIn(c.Request().Context(), requestUser.Password, user)
 	if intErr != nil {
 		if intErr.Err != nil {
 			h.log.Infof(intErr.Error())
 		}
+		if intErr.Code == customerror.Unauthorized {
+			h.loginFailed(c.Request().Context(), requestUser.Login, ip)
+		}
 
 		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
 	}
 
-	c.Response().Header().Set("Authorization", jwtHash)
+	if err := h.limiter.Success(c.Request().Context(), requestUser.Login); err != nil {
+		h.log.Errorf("login limiter reset error: %v", err)
+	}
+	h.setTokenHeaders(c, tokens)
 
 	return c.JSON(http.StatusOK, "Пользователь успешно аутентифицирован")
 }
 
+func (h *Handler) RefreshToken(c echo.Context) error {
+	request := &models.RequestRefresh{}
+	if err := c.Bind(request); err != nil {
+		h.log.Errorf("request failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+	if err := c.Validate(request); err != nil {
+		h.log.Errorf("validation failed: %v", err)
+
+		return echo.NewHTTPError(http.StatusBadRequest, "Неверный формат запроса")
+	}
+
+	tokens, intErr := h.uService.Refresh(c.Request().Context(), request.RefreshToken)
+	if intErr != nil {
+		if intErr.Err != nil {
+			h.log.Infof(intErr.Error())
+		}
+
+		return echo.NewHTTPError(h.convertCustomErrorToServerCode(intErr.Code), intErr.Message)
+	}
+
+	h.setTokenHeaders(c, tokens)
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+func (h *Handler) Logout(c echo.Context) error {
+	if err := h.jwt.Logout(c); err != nil {
+		h.log.Errorf("logout failed: %v", err)
+
+		return h.internalError(err)
+	}
+
+	return c.JSON(http.StatusOK, "Сеанс завершен")
+}
+
+func (h *Handler) loginFailed(ctx context.Context, login string, ip string) {
+	h.uService.LoginFailed(ctx, login)
+	if err := h.limiter.Failure(ctx, login, ip); err != nil {
+		h.log.Errorf("login limiter error: %v", err)
+	}
+}
+
+func (h *Handler) setTokenHeaders(c echo.Context, tokens *jwt.Tokens) {
+	c.Response().Header().Set("Authorization", tokens.Access)
+	c.Response().Header().Set(refreshTokenHeader, tokens.Refresh)
+}
+
 func (h *Handler) getRequestUser(c echo.Context) (*models.RequestUser, *echo.HTTPError) {
 	requestUser := &models.RequestUser{}
 	if err := c.Bind(requestUser); err != nil {
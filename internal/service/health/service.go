@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/service/transport"
+	"github.com/dontagr/loyalty/internal/store/migrations"
+	"github.com/dontagr/loyalty/internal/worker"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	defaultCheckTimeout = 2 * time.Second
+)
+
+type (
+	CheckResult struct {
+		Status   string `json:"status"`
+		Duration string `json:"duration"`
+		Error    string `json:"error,omitempty"`
+	}
+	Report struct {
+		Status string                  `json:"status"`
+		Checks map[string]*CheckResult `json:"checks"`
+	}
+	check struct {
+		name string
+		fn   func(ctx context.Context) error
+	}
+)
+
+type Service struct {
+	timeout time.Duration
+	checks  []check
+}
+
+func NewHealthService(cfg *config.Config, dbpool *pgretry.PgxRetry, migrator *migrations.Migrator, accrual *transport.HTTPManager, updater *worker.Updater) *Service {
+	timeout := time.Duration(cfg.Health.CheckTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	maxLag := time.Duration(cfg.Health.UpdaterMaxLag) * time.Second
+	if maxLag <= 0 {
+		maxLag = 3 * time.Duration(cfg.Service.UpdaterInterval) * time.Second
+	}
+
+	return newService(timeout,
+		check{name: "database", fn: dbpool.Ping},
+		check{name: "migrations", fn: func(ctx context.Context) error {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("не применено миграций: %d, первая %04d_%s", len(pending), pending[0].Version, pending[0].Name)
+			}
+
+			return nil
+		}},
+		check{name: "accrual", fn: accrual.Ping},
+		check{name: "updater", fn: func(_ context.Context) error {
+			lag := time.Since(updater.LastTick())
+			if lag > maxLag {
+				return fmt.Errorf("последний цикл опроса был %s назад", lag.Truncate(time.Second))
+			}
+
+			return nil
+		}},
+	)
+}
+
+func newService(timeout time.Duration, checks ...check) *Service {
+	return &Service{timeout: timeout, checks: checks}
+}
+
+// Ready выполняет все проверки параллельно, каждую со своим таймаутом, и собирает результат по каждой.
+func (s *Service) Ready(ctx context.Context) *Report {
+	report := &Report{Status: StatusOK, Checks: make(map[string]*CheckResult, len(s.checks))}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, c := range s.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			result := s.run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (s *Service) run(ctx context.Context, c check) *CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	result := &CheckResult{Status: StatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
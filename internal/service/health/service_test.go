@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadyAllChecksPass(t *testing.T) {
+	s := newService(time.Second,
+		check{name: "database", fn: func(context.Context) error { return nil }},
+		check{name: "accrual", fn: func(context.Context) error { return nil }},
+	)
+
+	report := s.Ready(context.Background())
+
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+}
+
+func TestReadyReportsFailedCheck(t *testing.T) {
+	s := newService(time.Second,
+		check{name: "database", fn: func(context.Context) error { return errors.New("connection refused") }},
+		check{name: "accrual", fn: func(context.Context) error { return nil }},
+	)
+
+	report := s.Ready(context.Background())
+
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusFail, report.Checks["database"].Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	assert.Equal(t, StatusOK, report.Checks["accrual"].Status)
+}
+
+func TestReadyAppliesTimeoutPerCheck(t *testing.T) {
+	s := newService(10*time.Millisecond,
+		check{name: "accrual", fn: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+
+	report := s.Ready(context.Background())
+
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["accrual"].Error)
+}
@@ -1,6 +1,9 @@
 package interfaces
 
 import (
+	"context"
+	"time"
+
 	"github.com/jackc/pgx/v5"
 
 	"github.com/dontagr/loyalty/internal/store/models"
@@ -8,26 +11,95 @@ import (
 
 type (
 	UserStore interface {
-		GetUser(login string) (*models.User, error)
-		GetTxUser(tx pgx.Tx, login string) (*models.User, error)
-		SaveUser(login string, passwordHash string) error
+		GetUser(ctx context.Context, login string) (*models.User, error)
+		GetTxUser(ctx context.Context, tx pgx.Tx, login string) (*models.User, error)
+		SaveUser(ctx context.Context, login string, passwordHash string) error
+		Block(ctx context.Context, userID int, reason string) error
+		Unblock(ctx context.Context, userID int) error
+		SetRole(ctx context.Context, login string, role string) (bool, error)
 	}
 	OrderStore interface {
-		GetOrder(orderID string) (*models.Order, error)
-		SaveOrder(orderID string, userID int) error
-		GetListByUserID(userID int) ([]*models.Order, error)
-		GetListForProcessing() ([]*models.Order, error)
-		UpdateOrder(order *models.Order) error
-		BlockOrder(orderID string) bool
-		UnblockOrder(orderID string) bool
+		GetOrder(ctx context.Context, orderID string) (*models.Order, error)
+		SaveOrder(ctx context.Context, orderID string, userID int) error
+		GetListByUserID(ctx context.Context, userID int) ([]*models.Order, error)
+		GetPageByUserID(ctx context.Context, userID int, filter *models.OrderFilter) ([]*models.Order, error)
+		ClaimForProcessing(ctx context.Context, owner string, leaseTTL time.Duration, limit int) ([]*models.Order, error)
+		ReleaseLease(ctx context.Context, orderID string, owner string) error
+		ScheduleNextAttempt(ctx context.Context, orderID string, nextAttempt time.Time) error
+		MarkStalled(ctx context.Context, createdBefore time.Time) (int64, error)
+		ResetForPolling(ctx context.Context, orderID string) (bool, error)
+		UpdateOrder(ctx context.Context, order *models.Order, credited func(tx pgx.Tx, amount int) error) (int, error)
 	}
 	WithdrawalStore interface {
-		BeginTX() (pgx.Tx, error)
-		RollbackTX(tx pgx.Tx)
-		CommitTX(tx pgx.Tx)
-		GetTotalWithdrawal(userID int) (float64, error)
-		GetWithdraw(orderID string) (*models.Withdrawal, error)
-		SaveWithdraw(tx pgx.Tx, withdrawal models.Withdrawal) error
-		GetWithdrawalListByUserID(userID int) ([]*models.Withdrawal, error)
+		RunInTx(ctx context.Context, fn func(tx pgx.Tx) error) error
+		GetTotalWithdrawal(ctx context.Context, userID int) (float64, error)
+		GetWithdraw(ctx context.Context, orderID string) (*models.Withdrawal, error)
+		GetTxWithdraw(ctx context.Context, tx pgx.Tx, orderID string) (*models.Withdrawal, error)
+		UpdateStatusTx(ctx context.Context, tx pgx.Tx, withdrawal *models.Withdrawal) error
+		ReverseTx(ctx context.Context, tx pgx.Tx, withdrawal *models.Withdrawal) error
+		SaveWithdraw(ctx context.Context, tx pgx.Tx, withdrawal models.Withdrawal) error
+		GetWithdrawalListByUserID(ctx context.Context, userID int) ([]*models.Withdrawal, error)
+		GetWithdrawalPageByUserID(ctx context.Context, userID int, filter *models.WithdrawalFilter) ([]*models.Withdrawal, error)
+		GetPeriodTotal(ctx context.Context, userID int, from *time.Time, to *time.Time) (int, error)
+	}
+	TransferStore interface {
+		RunInTx(ctx context.Context, fn func(tx pgx.Tx) error) error
+		GetSentTx(ctx context.Context, tx pgx.Tx, userID int, since time.Time) (int, int, error)
+		SaveTx(ctx context.Context, tx pgx.Tx, transfer *models.Transfer) error
+	}
+	TierStore interface {
+		GetUserTier(ctx context.Context, userID int, since time.Time) (*models.UserTier, error)
+		GetList(ctx context.Context, afterID int, since time.Time, limit int) ([]*models.UserTier, error)
+		UpdateTier(ctx context.Context, userID int, tier string) (bool, error)
+	}
+	IdempotencyStore interface {
+		GetTx(ctx context.Context, tx pgx.Tx, userID int, key string, ttl time.Duration) (*models.IdempotencyKey, error)
+		SaveTx(ctx context.Context, tx pgx.Tx, key *models.IdempotencyKey, ttl time.Duration) error
+	}
+	LedgerStore interface {
+		PostTx(ctx context.Context, tx pgx.Tx, entry *models.LedgerEntry) error
+		Adjust(ctx context.Context, entry *models.LedgerEntry, posted func(tx pgx.Tx) error) error
+		GetListByUserID(ctx context.Context, userID int, beforeID int64, limit int) ([]*models.LedgerEntry, error)
+		ExpireUserLots(ctx context.Context, userID int, now time.Time, expired func(tx pgx.Tx, entries []*models.LedgerEntry) error) ([]*models.LedgerEntry, error)
+		GetUsersWithExpiredLots(ctx context.Context, now time.Time, limit int) ([]int, error)
+		GetExpiring(ctx context.Context, userID int, before time.Time) ([]*models.ExpiringPoints, error)
+	}
+	ReconciliationStore interface {
+		CountUsers(ctx context.Context) (int, error)
+		GetDiscrepancies(ctx context.Context) ([]*models.Discrepancy, error)
+		Correct(ctx context.Context, discrepancy *models.Discrepancy, reason string) error
+		CreateRun(ctx context.Context, run *models.ReconciliationRun) error
+		SaveDiscrepancy(ctx context.Context, discrepancy *models.Discrepancy) error
+		FinishRun(ctx context.Context, run *models.ReconciliationRun) error
+		GetRuns(ctx context.Context, limit int) ([]*models.ReconciliationRun, error)
+	}
+	SessionStore interface {
+		CreateSession(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) (int64, error)
+		RotateRefreshToken(ctx context.Context, oldHash string, newHash string, expiresAt time.Time) (*models.Session, error)
+		RevokeSession(ctx context.Context, sessionID int64) error
+		IsSessionRevoked(ctx context.Context, sessionID int64) (bool, error)
+		RevokeUserSessions(ctx context.Context, userID int) ([]int64, error)
+	}
+	AuditStore interface {
+		Add(ctx context.Context, entry *models.AuditEntry) error
+		AddTx(ctx context.Context, tx pgx.Tx, entry *models.AuditEntry) error
+		GetList(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, error)
+		DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error)
+	}
+	RateLimitStore interface {
+		AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+		Lock(ctx context.Context, key string, until time.Time) error
+		GetLock(ctx context.Context, key string, now time.Time) (time.Time, error)
+		Reset(ctx context.Context, key string) error
+		DeleteExpired(ctx context.Context, before time.Time, now time.Time) (int64, error)
+	}
+	NonceStore interface {
+		Use(ctx context.Context, signature string, expiresAt time.Time) (bool, error)
+	}
+	OutboxStore interface {
+		AddTx(ctx context.Context, tx pgx.Tx, event *models.OutboxEvent) error
+		ClaimPending(ctx context.Context, owner string, leaseTTL time.Duration, limit int) ([]*models.OutboxEvent, error)
+		MarkPublished(ctx context.Context, eventID int64) error
+		MarkFailed(ctx context.Context, event *models.OutboxEvent, lastError string, nextAttempt time.Time) error
 	}
 )
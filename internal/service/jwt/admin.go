@@ -0,0 +1,28 @@
+package jwt
+
+import (
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+// GetAdminMiddleware пропускает только токены с ролью администратора, ставится после GetMiddleware.
+func (j *JWTService) GetAdminMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, ok := c.Get("user").(*jwt.Token)
+			if !ok {
+				return c.JSON(http.StatusForbidden, map[string]string{"message": "Доступ запрещен"})
+			}
+			claims, ok := token.Claims.(*JWTAuth)
+			if !ok || claims.Role != models.RoleAdmin {
+				return c.JSON(http.StatusForbidden, map[string]string{"message": "Доступ запрещен"})
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+func TestGetAdminMiddleware(t *testing.T) {
+	service := newTestService(t, nil)
+	handler := service.GetAdminMiddleware()(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	tests := []struct {
+		name string
+		role string
+		want int
+	}{
+		{name: "admin", role: models.RoleAdmin, want: http.StatusOK},
+		{name: "user", role: models.RoleUser, want: http.StatusForbidden},
+		{name: "no role", role: "", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest("GET", "/api/admin/users/user", nil), recorder)
+			c.Set("user", &jwt.Token{Claims: &JWTAuth{ID: 1, Login: "user", Role: tt.role}})
+
+			assert.NoError(t, handler(c))
+			assert.Equal(t, tt.want, recorder.Code)
+		})
+	}
+}
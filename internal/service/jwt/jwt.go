@@ -1,6 +1,13 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -9,47 +16,158 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
 	"github.com/dontagr/loyalty/internal/store/models"
 )
 
+const (
+	defaultAccessTokenTTL     = 15 * time.Minute
+	defaultRefreshTokenTTL    = 30 * 24 * time.Hour
+	defaultRevocationCacheTTL = 30 * time.Second
+	refreshTokenBytes         = 32
+)
+
+var ErrSessionRevoked = errors.New("session is revoked")
+
 type (
 	JWTService struct {
-		key string
+		key        string
+		keys       *keyRing
+		accessTTL  time.Duration
+		refreshTTL time.Duration
+		store      interfaces.SessionStore
+		revocation *revocationCache
 	}
 	JWTAuth struct {
-		ID    int    `json:"id"`
-		Login string `json:"login"`
+		ID        int    `json:"id"`
+		Login     string `json:"login"`
+		Role      string `json:"role,omitempty"`
+		SessionID int64  `json:"sid"`
 		jwt.RegisteredClaims
 	}
+	Tokens struct {
+		Access    string `json:"access_token"`
+		Refresh   string `json:"refresh_token"`
+		ExpiresIn int    `json:"expires_in"`
+	}
 )
 
-func NewJWTService(cnf *config.Config) *JWTService {
-	return &JWTService{key: cnf.Security.Key}
+func NewJWTService(cnf *config.Config, store interfaces.SessionStore) (*JWTService, error) {
+	keys, err := loadKeyRing(cnf.Security.SigningKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed load signing keys: %w", err)
+	}
+
+	return &JWTService{
+		key:        cnf.Security.Key,
+		keys:       keys,
+		accessTTL:  secondsOrDefault(cnf.Security.AccessTokenTTL, defaultAccessTokenTTL),
+		refreshTTL: secondsOrDefault(cnf.Security.RefreshTokenTTL, defaultRefreshTokenTTL),
+		store:      store,
+		revocation: newRevocationCache(store, secondsOrDefault(cnf.Security.RevocationCacheTTL, defaultRevocationCacheTTL)),
+	}, nil
+}
+
+func (j *JWTService) IssueTokens(ctx context.Context, ID int, Login string, Role string) (*Tokens, error) {
+	refresh, refreshHash, err := j.newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := j.store.CreateSession(ctx, ID, refreshHash, time.Now().Add(j.refreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := j.GetJWT(ID, Login, Role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{Access: access, Refresh: refresh, ExpiresIn: int(j.accessTTL.Seconds())}, nil
 }
 
-func (j *JWTService) GetJWT(ID int, Login string) (string, error) {
+func (j *JWTService) Refresh(ctx context.Context, refresh string) (*Tokens, error) {
+	newRefresh, newRefreshHash, err := j.newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := j.store.RotateRefreshToken(ctx, hashRefreshToken(refresh), newRefreshHash, time.Now().Add(j.refreshTTL))
+	if errors.Is(err, models.ErrRefreshTokenReused) {
+		j.revocation.markRevoked(session.ID)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := j.GetJWT(session.UserID, session.Login, session.Role, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{Access: access, Refresh: newRefresh, ExpiresIn: int(j.accessTTL.Seconds())}, nil
+}
+
+func (j *JWTService) Logout(c echo.Context) error {
+	claims := c.Get("user").(*jwt.Token).Claims.(*JWTAuth)
+	if err := j.store.RevokeSession(c.Request().Context(), claims.SessionID); err != nil {
+		return err
+	}
+	j.revocation.markRevoked(claims.SessionID)
+
+	return nil
+}
+
+// RevokeUser закрывает все сессии пользователя, токены перестают приниматься сразу на этой реплике
+// и в пределах RevocationCacheTTL на остальных.
+func (j *JWTService) RevokeUser(ctx context.Context, userID int) error {
+	sessions, err := j.store.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range sessions {
+		j.revocation.markRevoked(sessionID)
+	}
+
+	return nil
+}
+
+func (j *JWTService) GetJWT(ID int, Login string, Role string, sessionID int64) (string, error) {
 	claims := &JWTAuth{
 		ID,
 		Login,
+		Role,
+		sessionID,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.accessTTL)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	hash, err := token.SignedString([]byte(j.key))
+	if j.keys.empty() {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(j.key))
+	}
+
+	key, err := j.keys.signing(time.Now())
 	if err != nil {
 		return "", err
 	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+
+	return token.SignedString(key.private)
+}
 
-	return hash, err
+func (j *JWTService) GetJWKS() JWKS {
+	return j.keys.jwks(time.Now())
 }
 
 func (j *JWTService) GetJWTEchoConfig() echojwt.Config {
 	return echojwt.Config{
-		NewClaimsFunc: func(c echo.Context) jwt.Claims { return &JWTAuth{} },
-		SigningKey:    []byte(j.key),
-		TokenLookup:   "header:Authorization",
+		TokenLookup:    "header:Authorization",
+		ParseTokenFunc: j.parseToken,
 		ErrorHandler: func(c echo.Context, err error) error {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Пользователь не аутентифицирован"})
 		},
@@ -63,9 +181,76 @@ func (j *JWTService) GetUser(c echo.Context) *models.User {
 	return &models.User{
 		ID:    claims.ID,
 		Login: claims.Login,
+		Role:  claims.Role,
 	}
 }
 
 func (j *JWTService) GetMiddleware(jwtConfig echojwt.Config) echo.MiddlewareFunc {
 	return echojwt.WithConfig(jwtConfig)
 }
+
+func (j *JWTService) parseToken(c echo.Context, auth string) (interface{}, error) {
+	var token *jwt.Token
+	var err error
+	if j.keys.empty() {
+		token, err = jwt.ParseWithClaims(auth, &JWTAuth{}, func(token *jwt.Token) (interface{}, error) {
+			return []byte(j.key), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	} else {
+		token, err = jwt.ParseWithClaims(auth, &JWTAuth{}, j.verificationKey, jwt.WithValidMethods(j.keys.methods()))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(*JWTAuth)
+	if claims.SessionID == 0 {
+		return nil, ErrSessionRevoked
+	}
+	revoked, err := j.revocation.isRevoked(c.Request().Context(), claims.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed check session: %w", err)
+	}
+	if revoked {
+		return nil, ErrSessionRevoked
+	}
+
+	return token, nil
+}
+
+func (j *JWTService) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, exists := j.keys.verification(kid, time.Now())
+	if !exists {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+
+	return key.private.Public(), nil
+}
+
+func (j *JWTService) newRefreshToken() (string, string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func secondsOrDefault(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+
+	return time.Duration(seconds) * time.Second
+}
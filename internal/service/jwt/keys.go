@@ -0,0 +1,183 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/dontagr/loyalty/internal/config"
+)
+
+const minRSABits = 2048
+
+var ErrNoSigningKey = errors.New("no active signing key")
+
+type (
+	signingKey struct {
+		id         string
+		method     jwt.SigningMethod
+		private    crypto.Signer
+		activeFrom time.Time
+		expiresAt  time.Time
+	}
+	keyRing struct {
+		keys []*signingKey
+	}
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
+	JWK struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+	}
+)
+
+func loadKeyRing(cfg []config.SigningKey) (*keyRing, error) {
+	ring := &keyRing{}
+	seen := make(map[string]bool, len(cfg))
+	for _, keyCfg := range cfg {
+		if seen[keyCfg.ID] {
+			return nil, fmt.Errorf("duplicate signing key id %q", keyCfg.ID)
+		}
+		seen[keyCfg.ID] = true
+
+		key, err := loadSigningKey(keyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", keyCfg.ID, err)
+		}
+		ring.keys = append(ring.keys, key)
+	}
+
+	// новые ключи первыми: для подписи берется самый свежий уже активный ключ
+	sort.SliceStable(ring.keys, func(i, j int) bool {
+		return ring.keys[i].activeFrom.After(ring.keys[j].activeFrom)
+	})
+
+	return ring, nil
+}
+
+func loadSigningKey(cfg config.SigningKey) (*signingKey, error) {
+	body, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read pem: %w", err)
+	}
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, fmt.Errorf("no pem block in %s", cfg.Path)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	key := &signingKey{id: cfg.ID, activeFrom: cfg.ActiveFrom, expiresAt: cfg.ExpiresAt}
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("rsa key must be at least %d bits", minRSABits)
+		}
+		key.method = jwt.SigningMethodRS256
+		key.private = private
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.private = private
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+
+	return key, nil
+}
+
+func (k *keyRing) empty() bool {
+	return len(k.keys) == 0
+}
+
+func (k *keyRing) signing(now time.Time) (*signingKey, error) {
+	for _, key := range k.keys {
+		if !key.activeFrom.After(now) && key.validAt(now) {
+			return key, nil
+		}
+	}
+
+	return nil, ErrNoSigningKey
+}
+
+func (k *keyRing) verification(kid string, now time.Time) (*signingKey, bool) {
+	for _, key := range k.keys {
+		if key.id == kid && key.validAt(now) {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+func (k *keyRing) methods() []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, key := range k.keys {
+		alg := key.method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			result = append(result, alg)
+		}
+	}
+
+	return result
+}
+
+// jwks публикует все ключи, которыми еще можно проверить токен, включая запланированные на будущее,
+// чтобы потребители успели их закешировать до начала ротации.
+func (k *keyRing) jwks(now time.Time) JWKS {
+	result := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		if !key.validAt(now) {
+			continue
+		}
+
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		result.Keys = append(result.Keys, jwk)
+	}
+
+	return result
+}
+
+func (s *signingKey) validAt(now time.Time) bool {
+	return s.expiresAt.IsZero() || now.Before(s.expiresAt)
+}
@@ -0,0 +1,115 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+func writeKey(t *testing.T, private any) string {
+	t.Helper()
+
+	body, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: body}), 0o600)
+	require.NoError(t, err)
+
+	return path
+}
+
+func newTestContext() echo.Context {
+	return echo.New().NewContext(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+}
+
+func newTestService(t *testing.T, keys []config.SigningKey) *JWTService {
+	t.Helper()
+
+	cnf := &config.Config{}
+	cnf.Security.Key = "secret"
+	cnf.Security.SigningKeys = keys
+	service, err := NewJWTService(cnf, &fakeSessionStore{revoked: map[int64]bool{}})
+	require.NoError(t, err)
+
+	return service
+}
+
+func TestJWTService_KeyRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	oldKey := config.SigningKey{ID: "old", Path: writeKey(t, rsaKey), ActiveFrom: now.Add(-48 * time.Hour)}
+	newKey := config.SigningKey{ID: "new", Path: writeKey(t, edKey), ActiveFrom: now.Add(-time.Hour)}
+	nextKey := config.SigningKey{ID: "next", Path: writeKey(t, rsaKey), ActiveFrom: now.Add(time.Hour)}
+
+	oldService := newTestService(t, []config.SigningKey{oldKey})
+	oldToken, err := oldService.GetJWT(1, "user", models.RoleUser, 10)
+	require.NoError(t, err)
+
+	service := newTestService(t, []config.SigningKey{oldKey, newKey, nextKey})
+	token, err := service.GetJWT(1, "user", models.RoleUser, 10)
+	require.NoError(t, err)
+
+	parsed, err := service.parseToken(newTestContext(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "new", parsed.(*jwt.Token).Header["kid"])
+
+	_, err = service.parseToken(newTestContext(), oldToken)
+	assert.NoError(t, err, "token signed by a rotated out key must stay valid")
+
+	jwks := service.GetJWKS()
+	kids := make([]string, 0, len(jwks.Keys))
+	for _, key := range jwks.Keys {
+		kids = append(kids, key.Kid)
+	}
+	assert.ElementsMatch(t, []string{"old", "new", "next"}, kids)
+}
+
+func TestJWTService_ExpiredKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Now()
+	key := config.SigningKey{ID: "k1", Path: writeKey(t, rsaKey), ActiveFrom: now.Add(-time.Hour)}
+	token, err := newTestService(t, []config.SigningKey{key}).GetJWT(1, "user", models.RoleUser, 10)
+	require.NoError(t, err)
+
+	key.ExpiresAt = now.Add(-time.Minute)
+	service := newTestService(t, []config.SigningKey{key})
+
+	_, err = service.parseToken(newTestContext(), token)
+	assert.Error(t, err)
+	_, err = service.GetJWT(1, "user", models.RoleUser, 10)
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+	assert.Empty(t, service.GetJWKS().Keys)
+}
+
+func TestJWTService_SymmetricFallback(t *testing.T) {
+	service := newTestService(t, nil)
+
+	token, err := service.GetJWT(1, "user", models.RoleUser, 10)
+	require.NoError(t, err)
+
+	_, err = service.parseToken(newTestContext(), token)
+	assert.NoError(t, err)
+	assert.Empty(t, service.GetJWKS().Keys)
+}
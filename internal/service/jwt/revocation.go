@@ -0,0 +1,67 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+)
+
+const revocationCacheSweepSize = 10000
+
+type (
+	revocationCache struct {
+		mu      sync.RWMutex
+		ttl     time.Duration
+		store   interfaces.SessionStore
+		entries map[int64]revocationEntry
+	}
+	revocationEntry struct {
+		revoked   bool
+		checkedAt time.Time
+	}
+)
+
+func newRevocationCache(store interfaces.SessionStore, ttl time.Duration) *revocationCache {
+	return &revocationCache{
+		ttl:     ttl,
+		store:   store,
+		entries: make(map[int64]revocationEntry),
+	}
+}
+
+func (r *revocationCache) isRevoked(ctx context.Context, sessionID int64) (bool, error) {
+	r.mu.RLock()
+	entry, exists := r.entries[sessionID]
+	r.mu.RUnlock()
+	if exists && (entry.revoked || time.Since(entry.checkedAt) < r.ttl) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := r.store.IsSessionRevoked(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	r.set(sessionID, revoked)
+
+	return revoked, nil
+}
+
+func (r *revocationCache) markRevoked(sessionID int64) {
+	r.set(sessionID, true)
+}
+
+func (r *revocationCache) set(sessionID int64, revoked bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.entries) >= revocationCacheSweepSize {
+		for id, entry := range r.entries {
+			if time.Since(entry.checkedAt) >= r.ttl {
+				delete(r.entries, id)
+			}
+		}
+	}
+	r.entries[sessionID] = revocationEntry{revoked: revoked, checkedAt: time.Now()}
+}
@@ -0,0 +1,65 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+type fakeSessionStore struct {
+	revoked  map[int64]bool
+	sessions map[int][]int64
+	calls    int
+}
+
+func (f *fakeSessionStore) CreateSession(context.Context, int, string, time.Time) (int64, error) {
+	return 0, nil
+}
+func (f *fakeSessionStore) RotateRefreshToken(context.Context, string, string, time.Time) (*models.Session, error) {
+	return nil, nil
+}
+func (f *fakeSessionStore) RevokeSession(context.Context, int64) error { return nil }
+func (f *fakeSessionStore) IsSessionRevoked(_ context.Context, sessionID int64) (bool, error) {
+	f.calls++
+	return f.revoked[sessionID], nil
+}
+func (f *fakeSessionStore) RevokeUserSessions(_ context.Context, userID int) ([]int64, error) {
+	return f.sessions[userID], nil
+}
+
+func TestRevocationCache(t *testing.T) {
+	store := &fakeSessionStore{revoked: map[int64]bool{}}
+	cache := newRevocationCache(store, time.Hour)
+
+	revoked, err := cache.isRevoked(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	store.revoked[1] = true
+	revoked, _ = cache.isRevoked(context.Background(), 1)
+	assert.False(t, revoked, "fresh entry must be served from cache")
+	assert.Equal(t, 1, store.calls)
+
+	cache.markRevoked(1)
+	revoked, _ = cache.isRevoked(context.Background(), 1)
+	assert.True(t, revoked)
+	assert.Equal(t, 1, store.calls)
+}
+
+func TestRevocationCache_Expired(t *testing.T) {
+	store := &fakeSessionStore{revoked: map[int64]bool{}}
+	cache := newRevocationCache(store, time.Nanosecond)
+
+	_, _ = cache.isRevoked(context.Background(), 7)
+	store.revoked[7] = true
+	time.Sleep(time.Millisecond)
+
+	revoked, err := cache.isRevoked(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	assert.Equal(t, 2, store.calls)
+}
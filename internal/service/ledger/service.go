@@ -0,0 +1,149 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/audit"
+	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/service/models"
+	"github.com/dontagr/loyalty/internal/service/pagination"
+	storeModel "github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	defaultExpiringSoonDays = 30
+	defaultExpiryBatch      = 100
+)
+
+type Service struct {
+	store        interfaces.LedgerStore
+	auditService *audit.Service
+	expiringSoon time.Duration
+	expiryBatch  int
+	log          *zap.SugaredLogger
+}
+
+func NewLedgerService(cfg *config.Config, store interfaces.LedgerStore, auditService *audit.Service, log *zap.SugaredLogger) *Service {
+	expiringSoonDays := cfg.Points.ExpiringSoonDays
+	if expiringSoonDays <= 0 {
+		expiringSoonDays = defaultExpiringSoonDays
+	}
+	expiryBatch := cfg.Points.ExpiryBatch
+	if expiryBatch <= 0 {
+		expiryBatch = defaultExpiryBatch
+	}
+
+	return &Service{
+		store:        store,
+		auditService: auditService,
+		expiringSoon: time.Duration(expiringSoonDays) * 24 * time.Hour,
+		expiryBatch:  expiryBatch,
+		log:          log,
+	}
+}
+
+// GetExpiring возвращает баллы, которые сгорят в ближайшее окно, по дням и общей суммой.
+func (l *Service) GetExpiring(ctx context.Context, userID int) ([]*models.ResponseExpiringPoints, float64, error) {
+	list, err := l.store.GetExpiring(ctx, userID, time.Now().Add(l.expiringSoon))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := 0
+	result := make([]*models.ResponseExpiringPoints, 0, len(list))
+	for _, points := range list {
+		total += points.Amount
+		result = append(result, &models.ResponseExpiringPoints{Amount: float64(points.Amount) / 100, Date: points.ExpiresAt.Format(time.DateOnly)})
+	}
+
+	return result, float64(total) / 100, nil
+}
+
+// ExpirePoints сжигает просроченные лоты пачками по пользователям. Ошибка по одному пользователю
+// не останавливает остальных, он будет обработан при следующем запуске.
+func (l *Service) ExpirePoints(ctx context.Context) (int, int, error) {
+	now := time.Now()
+	users, total := 0, 0
+	failed := make(map[int]struct{})
+	for {
+		ids, err := l.store.GetUsersWithExpiredLots(ctx, now, l.expiryBatch+len(failed))
+		if err != nil {
+			return users, total, err
+		}
+
+		processed := 0
+		for _, userID := range ids {
+			if _, skip := failed[userID]; skip {
+				continue
+			}
+			processed++
+
+			amount := 0
+			_, err := l.store.ExpireUserLots(ctx, userID, now, func(tx pgx.Tx, entries []*storeModel.LedgerEntry) error {
+				amount = 0
+				for _, entry := range entries {
+					amount -= entry.Amount
+				}
+				details, _ := json.Marshal(map[string]int{"lots": len(entries)})
+
+				return l.auditService.RecordTx(ctx, tx, &storeModel.AuditEntry{Action: audit.ActionPointsExpire, Target: strconv.Itoa(userID), Amount: &amount, Details: details})
+			})
+			if err != nil {
+				l.log.Errorf("failed expire points of user %d: %v", userID, err)
+				failed[userID] = struct{}{}
+				continue
+			}
+			if amount == 0 {
+				continue
+			}
+
+			users++
+			total += amount
+		}
+		if processed == 0 {
+			return users, total, nil
+		}
+	}
+}
+
+func (l *Service) GetListByUser(ctx context.Context, user *storeModel.User, cursorStr string, limitStr string) (*models.ResponseLedger, *customerror.CustomError) {
+	limit, err := pagination.ParseLimit(limitStr)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.BadRequest, "Неверный формат запроса", err)
+	}
+	cursor, err := pagination.DecodeCursor(cursorStr)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.BadRequest, "Неверный формат запроса", err)
+	}
+
+	var beforeID int64
+	if cursor != nil {
+		beforeID, err = strconv.ParseInt(cursor.ID, 10, 64)
+		if err != nil {
+			return nil, customerror.NewCustomError(customerror.BadRequest, "Неверный формат запроса", pagination.ErrInvalidCursor)
+		}
+	}
+
+	list, err := l.store.GetListByUserID(ctx, user.ID, beforeID, limit+1)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get ledger: %w", err))
+	}
+
+	response := &models.ResponseLedger{Entries: list}
+	if len(list) > limit {
+		response.Entries = list[:limit]
+		last := response.Entries[limit-1]
+		response.NextCursor = pagination.EncodeCursor(pagination.Cursor{ID: strconv.FormatInt(last.ID, 10)})
+	}
+
+	return response, nil
+}
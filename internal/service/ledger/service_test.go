@@ -0,0 +1,109 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/servicetest"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+type fakeLedgerStore struct {
+	expired map[int][]int
+	failing map[int]bool
+}
+
+func (f *fakeLedgerStore) PostTx(context.Context, pgx.Tx, *models.LedgerEntry) error { return nil }
+
+func (f *fakeLedgerStore) Adjust(context.Context, *models.LedgerEntry, func(pgx.Tx) error) error {
+	return nil
+}
+
+func (f *fakeLedgerStore) GetListByUserID(context.Context, int, int64, int) ([]*models.LedgerEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeLedgerStore) GetExpiring(context.Context, int, time.Time) ([]*models.ExpiringPoints, error) {
+	return nil, nil
+}
+
+func (f *fakeLedgerStore) GetUsersWithExpiredLots(_ context.Context, _ time.Time, limit int) ([]int, error) {
+	var result []int
+	for userID := range f.expired {
+		if len(result) == limit {
+			break
+		}
+		result = append(result, userID)
+	}
+
+	return result, nil
+}
+
+func (f *fakeLedgerStore) ExpireUserLots(_ context.Context, userID int, _ time.Time, expired func(pgx.Tx, []*models.LedgerEntry) error) ([]*models.LedgerEntry, error) {
+	if f.failing[userID] {
+		return nil, errors.New("deadlock detected")
+	}
+
+	var entries []*models.LedgerEntry
+	for _, amount := range f.expired[userID] {
+		entries = append(entries, &models.LedgerEntry{UserID: userID, EntryType: models.LedgerExpiry, Amount: -amount})
+	}
+	delete(f.expired, userID)
+	if len(entries) > 0 {
+		if err := expired(nil, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+func TestExpirePoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		expired map[int][]int
+		failing map[int]bool
+		users   int
+		total   int
+		left    map[int][]int
+	}{
+		{name: "nothing expired", left: map[int][]int{}},
+		{name: "all users expire", expired: map[int][]int{1: {100, 50}, 2: {30}}, users: 2, total: 180, left: map[int][]int{}},
+		{
+			name:    "failed user is skipped",
+			expired: map[int][]int{1: {100, 50}, 2: {30}, 3: {70}},
+			failing: map[int]bool{2: true},
+			users:   2,
+			total:   220,
+			left:    map[int][]int{2: {30}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expired := make(map[int][]int, len(tt.expired))
+			for userID, lots := range tt.expired {
+				expired[userID] = lots
+			}
+			store := &fakeLedgerStore{expired: expired, failing: tt.failing}
+			auditStore := &servicetest.AuditStore{}
+			cfg := &config.Config{Points: config.Points{ExpiryBatch: 1}}
+			service := NewLedgerService(cfg, store, servicetest.NewAuditService(auditStore), zap.NewNop().Sugar())
+
+			users, total, err := service.ExpirePoints(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.users, users)
+			assert.Equal(t, tt.total, total)
+			assert.Len(t, auditStore.Entries, tt.users)
+			assert.Equal(t, tt.left, store.expired)
+		})
+	}
+}
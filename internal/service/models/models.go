@@ -1,10 +1,20 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+
+	storeModels "github.com/dontagr/loyalty/internal/store/models"
+)
+
 type (
 	RequestUser struct {
 		Login    string `json:"login" validate:"required,alphanum|email"`
 		Password string `json:"password" validate:"required"`
 	}
+	RequestRefresh struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
 	RequestOrder struct {
 		ID string `validate:"required,number,algLuna"`
 	}
@@ -13,7 +23,98 @@ type (
 		Sum   float64 `json:"sum" validate:"required,floatGtZero"`
 	}
 	ResponceWithdraw struct {
-		Balance    float64 `json:"current"`
-		Withdrawal float64 `json:"withdrawn"`
+		Balance      float64                   `json:"current"`
+		Withdrawal   float64                   `json:"withdrawn"`
+		ExpiringSoon float64                   `json:"expiring_soon"`
+		Expirations  []*ResponseExpiringPoints `json:"expirations,omitempty"`
+		Tier         *ResponseTier             `json:"tier,omitempty"`
+	}
+	// ResponseTier.Accrued - начисления по заказам за последние 12 месяцев, ToNext - сколько
+	// не хватает до следующего уровня. Новый уровень присваивается при ночном пересчете.
+	ResponseTier struct {
+		Name       string  `json:"name"`
+		Multiplier float64 `json:"multiplier"`
+		Accrued    float64 `json:"accrued"`
+		Next       string  `json:"next,omitempty"`
+		ToNext     float64 `json:"to_next,omitempty"`
+	}
+	RequestTransfer struct {
+		Login string  `json:"login" validate:"required"`
+		Sum   float64 `json:"sum" validate:"required,floatGtZero"`
+	}
+	ResponseTransfer struct {
+		ID             int64     `json:"id"`
+		To             string    `json:"to"`
+		Sum            float64   `json:"sum"`
+		CreateDateTime time.Time `json:"created_at"`
+	}
+	ResponseExpiringPoints struct {
+		Amount float64 `json:"amount"`
+		Date   string  `json:"date"`
+	}
+	RequestOrderList struct {
+		Cursor string   `query:"cursor"`
+		Limit  string   `query:"limit"`
+		Status []string `query:"status"`
+		From   string   `query:"from"`
+		To     string   `query:"to"`
+	}
+	ResponseOrderList struct {
+		Orders     []*storeModels.Order `json:"orders"`
+		NextCursor string               `json:"next_cursor,omitempty"`
+	}
+	RequestWithdrawalList struct {
+		Cursor string `query:"cursor"`
+		Limit  string `query:"limit"`
+		From   string `query:"from"`
+		To     string `query:"to"`
+	}
+	ResponseWithdrawalList struct {
+		Withdrawals []*storeModels.Withdrawal `json:"withdrawals"`
+		Total       float64                   `json:"total"`
+		NextCursor  string                    `json:"next_cursor,omitempty"`
+	}
+	RequestAdminReason struct {
+		Reason string `json:"reason" validate:"max=1000"`
+	}
+	RequestPartnerReversal struct {
+		Reason string `json:"reason" validate:"max=1000"`
+	}
+	RequestBlockUser struct {
+		Reason string `json:"reason" validate:"required,max=1000"`
+	}
+	RequestBalanceAdjustment struct {
+		Amount float64 `json:"amount" validate:"required"`
+		Reason string  `json:"reason" validate:"required,max=1000"`
+	}
+	ResponseAdminUser struct {
+		ID        int        `json:"id"`
+		Login     string     `json:"login"`
+		Role      string     `json:"role"`
+		Balance   float64    `json:"balance"`
+		BlockedAt *time.Time `json:"blocked_at,omitempty"`
+	}
+	// IdempotentResponse - ответ, сохраненный под Idempotency-Key, Replayed выставлен для повторной отдачи
+	IdempotentResponse struct {
+		StatusCode int
+		Body       json.RawMessage
+		Replayed   bool
+	}
+	RequestAuditList struct {
+		Cursor  string `query:"cursor"`
+		Limit   string `query:"limit"`
+		ActorID string `query:"actor_id"`
+		Action  string `query:"action"`
+		Target  string `query:"target"`
+		From    string `query:"from"`
+		To      string `query:"to"`
+	}
+	ResponseAuditList struct {
+		Entries    []*storeModels.AuditEntry `json:"entries"`
+		NextCursor string                    `json:"next_cursor,omitempty"`
+	}
+	ResponseLedger struct {
+		Entries    []*storeModels.LedgerEntry `json:"entries"`
+		NextCursor string                     `json:"next_cursor,omitempty"`
 	}
 )
@@ -1,25 +1,33 @@
 package order
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
+	"github.com/dontagr/loyalty/internal/metrics"
+	"github.com/dontagr/loyalty/internal/service/audit"
 	"github.com/dontagr/loyalty/internal/service/customerror"
 	"github.com/dontagr/loyalty/internal/service/interfaces"
+	serviceModels "github.com/dontagr/loyalty/internal/service/models"
+	"github.com/dontagr/loyalty/internal/service/pagination"
 	"github.com/dontagr/loyalty/internal/store/models"
 )
 
 type Service struct {
-	store interfaces.OrderStore
+	store        interfaces.OrderStore
+	metrics      *metrics.Metrics
+	auditService *audit.Service
 }
 
-func NewOrderService(store interfaces.OrderStore) *Service {
-	return &Service{store: store}
+func NewOrderService(store interfaces.OrderStore, m *metrics.Metrics, auditService *audit.Service) *Service {
+	return &Service{store: store, metrics: m, auditService: auditService}
 }
 
-func (o *Service) CreateOrder(orderID string, user *models.User) (bool, *customerror.CustomError) {
-	order, err := o.store.GetOrder(orderID)
+func (o *Service) CreateOrder(ctx context.Context, orderID string, user *models.User) (bool, *customerror.CustomError) {
+	order, err := o.store.GetOrder(ctx, orderID)
 	if err != nil {
-		return false, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get order: %v", err))
+		return false, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get order: %w", err))
 	}
 	if order.UserID != 0 && order.UserID != user.ID {
 		return false, customerror.NewCustomError(customerror.Conflict, "Номер заказа уже был загружен другим пользователем", nil)
@@ -28,19 +36,82 @@ func (o *Service) CreateOrder(orderID string, user *models.User) (bool, *custome
 		return false, nil
 	}
 
-	err = o.store.SaveOrder(orderID, user.ID)
+	err = o.store.SaveOrder(ctx, orderID, user.ID)
 	if err != nil {
-		return false, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed save order: %v", err))
+		return false, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed save order: %w", err))
 	}
+	o.metrics.OrderUploaded()
+	o.auditService.Record(ctx, &models.AuditEntry{ActorID: &user.ID, Action: audit.ActionOrderUpload, Target: orderID})
 
 	return true, nil
 }
 
-func (o *Service) GetListByUser(user *models.User) ([]*models.Order, *customerror.CustomError) {
-	list, err := o.store.GetListByUserID(user.ID)
+func (o *Service) GetListByUser(ctx context.Context, user *models.User) ([]*models.Order, *customerror.CustomError) {
+	list, err := o.store.GetListByUserID(ctx, user.ID)
 	if err != nil {
-		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get list order: %v", err))
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get list order: %w", err))
 	}
 
 	return list, nil
 }
+
+func (o *Service) GetPageByUser(ctx context.Context, user *models.User, request *serviceModels.RequestOrderList) (*serviceModels.ResponseOrderList, *customerror.CustomError) {
+	filter, err := o.buildFilter(request)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.BadRequest, "Неверный формат запроса", err)
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	list, err := o.store.GetPageByUserID(ctx, user.ID, filter)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get list order: %w", err))
+	}
+
+	response := &serviceModels.ResponseOrderList{Orders: list}
+	if len(list) > limit {
+		response.Orders = list[:limit]
+		last := response.Orders[limit-1]
+		response.NextCursor = pagination.EncodeCursor(pagination.Cursor{Time: last.CreateDateTime, ID: last.ID})
+	}
+
+	return response, nil
+}
+
+func (o *Service) buildFilter(request *serviceModels.RequestOrderList) (*models.OrderFilter, error) {
+	var err error
+	filter := &models.OrderFilter{}
+
+	filter.Limit, err = pagination.ParseLimit(request.Limit)
+	if err != nil {
+		return nil, err
+	}
+	filter.From, err = pagination.ParseTime(request.From)
+	if err != nil {
+		return nil, err
+	}
+	filter.To, err = pagination.ParseTime(request.To)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := pagination.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		filter.After = &models.PageKey{Time: cursor.Time, ID: cursor.ID}
+	}
+
+	for _, value := range request.Status {
+		for _, str := range strings.Split(value, ",") {
+			status, exists := models.ParseOrderStatus(strings.ToUpper(strings.TrimSpace(str)))
+			if !exists {
+				return nil, fmt.Errorf("unknown order status %q", str)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,75 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidTime   = errors.New("invalid time, RFC3339 expected")
+)
+
+type Cursor struct {
+	Time time.Time `json:"t,omitempty"`
+	ID   string    `json:"id"`
+}
+
+func EncodeCursor(cursor Cursor) string {
+	body, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	if err := json.Unmarshal(body, cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+func ParseLimit(value string) (int, error) {
+	if value == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, ErrInvalidLimit
+	}
+
+	return min(limit, MaxLimit), nil
+}
+
+func ParseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, ErrInvalidTime
+	}
+
+	return &t, nil
+}
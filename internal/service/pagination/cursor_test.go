@@ -0,0 +1,86 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor := Cursor{Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), ID: "12345678903"}
+
+	got, err := DecodeCursor(EncodeCursor(cursor))
+
+	assert.NoError(t, err)
+	assert.True(t, cursor.Time.Equal(got.Time))
+	assert.Equal(t, cursor.ID, got.ID)
+}
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty", value: "", wantNil: true},
+		{name: "not base64", value: "%%%", wantErr: true},
+		{name: "not json", value: "bm90LWpzb24", wantErr: true},
+		{name: "without id", value: EncodeCursor(Cursor{}), wantErr: true},
+		{name: "valid", value: EncodeCursor(Cursor{ID: "1"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCursor)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNil, got == nil)
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "default", value: "", want: DefaultLimit},
+		{name: "explicit", value: "10", want: 10},
+		{name: "capped", value: "100000", want: MaxLimit},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "negative", value: "-5", wantErr: true},
+		{name: "not a number", value: "ten", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLimit)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	got, err := ParseTime("")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = ParseTime("2024-05-01T10:00:00+03:00")
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC).Equal(*got))
+
+	_, err = ParseTime("01.05.2024")
+	assert.ErrorIs(t, err, ErrInvalidTime)
+}
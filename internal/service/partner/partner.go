@@ -0,0 +1,95 @@
+package partner
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+)
+
+const (
+	TimestampHeader = "X-Partner-Timestamp"
+	SignatureHeader = "X-Partner-Signature"
+
+	defaultSignatureTolerance = 5 * time.Minute
+)
+
+type Service struct {
+	secret    []byte
+	tolerance time.Duration
+	nonces    interfaces.NonceStore
+	now       func() time.Time
+}
+
+func NewPartnerService(cfg *config.Config, nonces interfaces.NonceStore) *Service {
+	tolerance := time.Duration(cfg.Partner.SignatureTolerance) * time.Second
+	if tolerance <= 0 {
+		tolerance = defaultSignatureTolerance
+	}
+
+	return &Service{secret: []byte(cfg.Partner.CallbackSecret), tolerance: tolerance, nonces: nonces, now: time.Now}
+}
+
+// GetMiddleware проверяет подпись обратного вызова партнера: HMAC-SHA256 общего секрета
+// от "<timestamp>.<метод>.<путь>.<тело запроса>" в hex. Путь с номером заказа и действием не дает
+// применить подпись к другому заказу или действию, метка времени в секундах ограничивает окно,
+// а использованные в нем подписи запоминаются, поэтому перехваченный вызов нельзя повторить.
+// Без настроенного секрета все вызовы отклоняются.
+func (p *Service) GetMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if len(p.secret) == 0 {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Неверная подпись запроса"})
+			}
+
+			timestamp := c.Request().Header.Get(TimestampHeader)
+			unix, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil || p.now().Sub(time.Unix(unix, 0)).Abs() > p.tolerance {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Неверная подпись запроса"})
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"message": "Неверный формат запроса"})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			request := c.Request()
+			signature, err := hex.DecodeString(request.Header.Get(SignatureHeader))
+			if err != nil || !hmac.Equal(signature, p.Sign(timestamp, request.Method, request.URL.Path, body)) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Неверная подпись запроса"})
+			}
+
+			fresh, err := p.nonces.Use(request.Context(), hex.EncodeToString(signature), time.Unix(unix, 0).Add(p.tolerance))
+			if err != nil {
+				return fmt.Errorf("failed save partner signature: %w", err)
+			}
+			if !fresh {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Подпись запроса уже использована"})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func (p *Service) Sign(timestamp string, method string, path string, body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	for _, part := range []string{timestamp, method, path} {
+		mac.Write([]byte(part))
+		mac.Write([]byte("."))
+	}
+	mac.Write(body)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,94 @@
+package partner
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNonceStore struct {
+	used map[string]time.Time
+}
+
+func (f *fakeNonceStore) Use(_ context.Context, signature string, expiresAt time.Time) (bool, error) {
+	if _, ok := f.used[signature]; ok {
+		return false, nil
+	}
+	f.used[signature] = expiresAt
+
+	return true, nil
+}
+
+func TestGetMiddleware(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	service := &Service{secret: []byte("secret"), tolerance: time.Minute, now: func() time.Time { return now }}
+	const (
+		reversePath  = "/api/partner/withdrawals/79927398713/reverse"
+		completePath = "/api/partner/withdrawals/79927398713/complete"
+		otherPath    = "/api/partner/withdrawals/2377225624/complete"
+	)
+	body := `{"reason":"cancelled"}`
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	valid := hex.EncodeToString(service.Sign(timestamp, http.MethodPost, reversePath, []byte(body)))
+	complete := hex.EncodeToString(service.Sign(timestamp, http.MethodPost, completePath, nil))
+	stale := strconv.FormatInt(now.Add(-2*time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		path      string
+		timestamp string
+		signature string
+		body      string
+		want      int
+		replay    int
+	}{
+		{name: "valid", secret: "secret", path: reversePath, timestamp: timestamp, signature: valid, body: body, want: http.StatusOK},
+		{name: "valid without body", secret: "secret", path: completePath, timestamp: timestamp, signature: complete, want: http.StatusOK},
+		{name: "replayed", secret: "secret", path: completePath, timestamp: timestamp, signature: complete, want: http.StatusOK, replay: http.StatusUnauthorized},
+		{name: "tampered body", secret: "secret", path: reversePath, timestamp: timestamp, signature: valid, body: `{"reason":"other"}`, want: http.StatusUnauthorized},
+		{name: "other order", secret: "secret", path: otherPath, timestamp: timestamp, signature: complete, want: http.StatusUnauthorized},
+		{name: "other action", secret: "secret", path: reversePath, timestamp: timestamp, signature: complete, want: http.StatusUnauthorized},
+		{name: "stale timestamp", secret: "secret", path: reversePath, timestamp: stale, signature: hex.EncodeToString(service.Sign(stale, http.MethodPost, reversePath, []byte(body))), body: body, want: http.StatusUnauthorized},
+		{name: "missing signature", secret: "secret", path: reversePath, timestamp: timestamp, body: body, want: http.StatusUnauthorized},
+		{name: "secret not configured", path: reversePath, timestamp: timestamp, signature: valid, body: body, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{secret: []byte(tt.secret), tolerance: service.tolerance, nonces: &fakeNonceStore{used: map[string]time.Time{}}, now: service.now}
+			var received string
+			handler := s.GetMiddleware()(func(c echo.Context) error {
+				payload, _ := io.ReadAll(c.Request().Body)
+				received = string(payload)
+
+				return c.NoContent(http.StatusOK)
+			})
+			send := func() int {
+				request := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+				request.Header.Set(TimestampHeader, tt.timestamp)
+				request.Header.Set(SignatureHeader, tt.signature)
+				recorder := httptest.NewRecorder()
+
+				assert.NoError(t, handler(echo.New().NewContext(request, recorder)))
+				return recorder.Code
+			}
+
+			assert.Equal(t, tt.want, send())
+			if tt.want == http.StatusOK {
+				assert.Equal(t, tt.body, received)
+			}
+			if tt.replay != 0 {
+				assert.Equal(t, tt.replay, send())
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+type FilePublisher struct {
+	mu     sync.Mutex
+	writer io.Writer
+	file   *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening file %s: %v", path, err)
+	}
+
+	return &FilePublisher{writer: file, file: file}, nil
+}
+
+func NewStdoutPublisher() *FilePublisher {
+	return &FilePublisher{writer: os.Stdout}
+}
+
+func (p *FilePublisher) Publish(_ context.Context, event *models.OutboxEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.writer.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("writing event: %v", err)
+	}
+	if p.file != nil {
+		return p.file.Sync()
+	}
+
+	return nil
+}
+
+func (p *FilePublisher) Close() error {
+	if p.file == nil {
+		return nil
+	}
+
+	return p.file.Close()
+}
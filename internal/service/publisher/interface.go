@@ -0,0 +1,13 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+type (
+	Publisher interface {
+		Publish(ctx context.Context, event *models.OutboxEvent) error
+	}
+)
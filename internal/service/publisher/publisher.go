@@ -0,0 +1,34 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/dontagr/loyalty/internal/config"
+)
+
+func NewPublisher(cfg *config.Config, lc fx.Lifecycle) (Publisher, error) {
+	switch cfg.Outbox.Sink {
+	case "webhook":
+		return NewWebhookPublisher(cfg.Outbox.WebhookURL, time.Duration(cfg.Outbox.Timeout)*time.Second), nil
+	case "file":
+		p, err := NewFilePublisher(cfg.Outbox.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		lc.Append(fx.Hook{
+			OnStop: func(_ context.Context) error {
+				return p.Close()
+			},
+		})
+
+		return p, nil
+	case "stdout", "":
+		return NewStdoutPublisher(), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox sink %q", cfg.Outbox.Sink)
+	}
+}
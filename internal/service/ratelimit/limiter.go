@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+)
+
+const (
+	defaultWindow  = 15 * time.Minute
+	defaultLockout = 15 * time.Minute
+	loginKeyPrefix = "login:"
+	ipKeyPrefix    = "ip:"
+)
+
+type (
+	Policy struct {
+		Window      time.Duration
+		MaxFailures int
+	}
+	LoginLimiter struct {
+		store   interfaces.RateLimitStore
+		login   Policy
+		ip      Policy
+		lockout time.Duration
+		now     func() time.Time
+	}
+)
+
+func NewLoginLimiter(cfg *config.Config, store interfaces.RateLimitStore) *LoginLimiter {
+	return &LoginLimiter{
+		store: store,
+		login: Policy{
+			Window:      secondsOrDefault(cfg.RateLimit.LoginWindow, defaultWindow),
+			MaxFailures: cfg.RateLimit.LoginMaxFailures,
+		},
+		ip: Policy{
+			Window:      secondsOrDefault(cfg.RateLimit.IPWindow, defaultWindow),
+			MaxFailures: cfg.RateLimit.IPMaxFailures,
+		},
+		lockout: secondsOrDefault(cfg.RateLimit.Lockout, defaultLockout),
+		now:     time.Now,
+	}
+}
+
+// Check возвращает время до снятия блокировки, если логин или адрес сейчас заблокированы.
+func (l *LoginLimiter) Check(ctx context.Context, login string, ip string) (time.Duration, error) {
+	now := l.now()
+	var retryAfter time.Duration
+	for _, key := range l.keys(login, ip) {
+		until, err := l.store.GetLock(ctx, key, now)
+		if err != nil {
+			return 0, err
+		}
+		if wait := until.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	return retryAfter, nil
+}
+
+func (l *LoginLimiter) Failure(ctx context.Context, login string, ip string) error {
+	now := l.now()
+	for _, item := range []struct {
+		key    string
+		policy Policy
+	}{
+		{loginKeyPrefix + login, l.login},
+		{ipKeyPrefix + ip, l.ip},
+	} {
+		if item.policy.MaxFailures <= 0 {
+			continue
+		}
+
+		count, err := l.store.AddFailure(ctx, item.key, now, item.policy.Window)
+		if err != nil {
+			return err
+		}
+		if count >= item.policy.MaxFailures {
+			if err := l.store.Lock(ctx, item.key, now.Add(l.lockout)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Success сбрасывает счетчик логина; счетчик адреса не сбрасывается, иначе перебор по многим логинам
+// с одного адреса можно было бы обнулять входом в собственную учетную запись.
+func (l *LoginLimiter) Success(ctx context.Context, login string) error {
+	if l.login.MaxFailures <= 0 {
+		return nil
+	}
+
+	return l.store.Reset(ctx, loginKeyPrefix+login)
+}
+
+// Cleanup удаляет попытки, вышедшие за самое длинное окно, и истекшие блокировки.
+func (l *LoginLimiter) Cleanup(ctx context.Context) (int64, error) {
+	now := l.now()
+	window := l.login.Window
+	if l.ip.Window > window {
+		window = l.ip.Window
+	}
+
+	return l.store.DeleteExpired(ctx, now.Add(-window), now)
+}
+
+func (l *LoginLimiter) keys(login string, ip string) []string {
+	var keys []string
+	if l.login.MaxFailures > 0 {
+		keys = append(keys, loginKeyPrefix+login)
+	}
+	if l.ip.MaxFailures > 0 {
+		keys = append(keys, ipKeyPrefix+ip)
+	}
+
+	return keys
+}
+
+func secondsOrDefault(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dontagr/loyalty/internal/config"
+)
+
+func newTestLimiter(now *time.Time) *LoginLimiter {
+	cfg := &config.Config{RateLimit: config.RateLimit{
+		LoginWindow:      60,
+		LoginMaxFailures: 3,
+		IPWindow:         60,
+		IPMaxFailures:    5,
+		Lockout:          300,
+	}}
+	limiter := NewLoginLimiter(cfg, NewMemoryStore())
+	limiter.now = func() time.Time { return *now }
+
+	return limiter
+}
+
+func TestLoginLimiterLocksLogin(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+
+	for i := 0; i < 3; i++ {
+		retryAfter, err := limiter.Check(context.Background(), "user", "10.0.0.1")
+		require.NoError(t, err)
+		assert.Zero(t, retryAfter)
+		require.NoError(t, limiter.Failure(context.Background(), "user", "10.0.0.1"))
+	}
+
+	retryAfter, err := limiter.Check(context.Background(), "user", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, retryAfter)
+
+	retryAfter, err = limiter.Check(context.Background(), "other", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	now = now.Add(5 * time.Minute)
+	retryAfter, err = limiter.Check(context.Background(), "user", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+}
+
+func TestLoginLimiterSlidingWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+
+	require.NoError(t, limiter.Failure(context.Background(), "user", "10.0.0.1"))
+	require.NoError(t, limiter.Failure(context.Background(), "user", "10.0.0.1"))
+	now = now.Add(61 * time.Second)
+	require.NoError(t, limiter.Failure(context.Background(), "user", "10.0.0.1"))
+
+	retryAfter, err := limiter.Check(context.Background(), "user", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+}
+
+func TestLoginLimiterLocksIPAcrossLogins(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+
+	for _, login := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, limiter.Failure(context.Background(), login, "10.0.0.1"))
+	}
+	require.NoError(t, limiter.Success(context.Background(), "f"))
+
+	retryAfter, err := limiter.Check(context.Background(), "f", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, retryAfter)
+}
+
+func TestLoginLimiterSuccessResetsLogin(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+
+	require.NoError(t, limiter.Failure(context.Background(), "user", "10.0.0.1"))
+	require.NoError(t, limiter.Failure(context.Background(), "user", "10.0.0.2"))
+	require.NoError(t, limiter.Success(context.Background(), "user"))
+	require.NoError(t, limiter.Failure(context.Background(), "user", "10.0.0.3"))
+
+	retryAfter, err := limiter.Check(context.Background(), "user", "10.0.0.4")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+}
+
+func TestLoginLimiterCleanup(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+	store := limiter.store.(*MemoryStore)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, limiter.Failure(context.Background(), "user", "10.0.0.1"))
+	}
+
+	now = now.Add(61 * time.Second)
+	deleted, err := limiter.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), deleted)
+	assert.Empty(t, store.events)
+
+	retryAfter, err := limiter.Check(context.Background(), "user", "10.0.0.2")
+	require.NoError(t, err)
+	assert.NotZero(t, retryAfter)
+
+	now = now.Add(5 * time.Minute)
+	deleted, err = limiter.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Empty(t, store.locks)
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const memoryStoreSweepSize = 10000
+
+type (
+	MemoryStore struct {
+		mu        sync.Mutex
+		maxWindow time.Duration
+		events    map[string][]time.Time
+		locks     map[string]time.Time
+	}
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		events: make(map[string][]time.Time),
+		locks:  make(map[string]time.Time),
+	}
+}
+
+func (m *MemoryStore) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if window > m.maxWindow {
+		m.maxWindow = window
+	}
+	if len(m.events) >= memoryStoreSweepSize {
+		m.sweep(now)
+	}
+
+	events := prune(m.events[key], now.Add(-window))
+	events = append(events, now)
+	m.events[key] = events
+
+	return len(events), nil
+}
+
+func (m *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if until.After(m.locks[key]) {
+		m.locks[key] = until
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) GetLock(ctx context.Context, key string, now time.Time) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	until, exists := m.locks[key]
+	if !exists {
+		return time.Time{}, nil
+	}
+	if !until.After(now) {
+		delete(m.locks, key)
+		return time.Time{}, nil
+	}
+
+	return until, nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.events, key)
+	delete(m.locks, key)
+
+	return nil
+}
+
+func (m *MemoryStore) DeleteExpired(ctx context.Context, before time.Time, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteExpired(before, now), nil
+}
+
+func (m *MemoryStore) sweep(now time.Time) {
+	m.deleteExpired(now.Add(-m.maxWindow), now)
+}
+
+func (m *MemoryStore) deleteExpired(before time.Time, now time.Time) int64 {
+	var deleted int64
+	for key, events := range m.events {
+		pruned := prune(events, before)
+		deleted += int64(len(events) - len(pruned))
+		if len(pruned) == 0 {
+			delete(m.events, key)
+		} else {
+			m.events[key] = pruned
+		}
+	}
+	for key, until := range m.locks {
+		if !until.After(now) {
+			delete(m.locks, key)
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+func prune(events []time.Time, after time.Time) []time.Time {
+	idx := 0
+	for idx < len(events) && !events[idx].After(after) {
+		idx++
+	}
+
+	return events[idx:]
+}
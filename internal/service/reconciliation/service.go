@@ -0,0 +1,66 @@
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const reportLimit = 10
+
+type Service struct {
+	store interfaces.ReconciliationStore
+}
+
+func NewReconciliationService(store interfaces.ReconciliationStore) *Service {
+	return &Service{store: store}
+}
+
+func (r *Service) Run(ctx context.Context, autoCorrect bool) (*models.ReconciliationRun, error) {
+	run := &models.ReconciliationRun{AutoCorrect: autoCorrect}
+	err := r.store.CreateRun(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+
+	run.UsersChecked, err = r.store.CountUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	run.Discrepancies, err = r.store.GetDiscrepancies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, discrepancy := range run.Discrepancies {
+		discrepancy.RunID = run.ID
+		if autoCorrect {
+			err = r.store.Correct(ctx, discrepancy, fmt.Sprintf("reconciliation run #%d", run.ID))
+			if err != nil {
+				return nil, fmt.Errorf("failed correct balance of user %d: %v", discrepancy.UserID, err)
+			}
+			if discrepancy.Corrected {
+				run.Corrected++
+			}
+		}
+
+		err = r.store.SaveDiscrepancy(ctx, discrepancy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = r.store.FinishRun(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func (r *Service) GetReport(ctx context.Context) ([]*models.ReconciliationRun, error) {
+	return r.store.GetRuns(ctx, reportLimit)
+}
@@ -0,0 +1,67 @@
+// Package servicetest содержит общие заглушки хранилищ для тестов сервисов.
+package servicetest
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/service/audit"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+// AuditStore запоминает записанные события аудита.
+type AuditStore struct {
+	Entries []*models.AuditEntry
+}
+
+func (f *AuditStore) Add(_ context.Context, entry *models.AuditEntry) error {
+	f.Entries = append(f.Entries, entry)
+	return nil
+}
+
+func (f *AuditStore) AddTx(ctx context.Context, _ pgx.Tx, entry *models.AuditEntry) error {
+	return f.Add(ctx, entry)
+}
+
+func (f *AuditStore) GetList(context.Context, *models.AuditFilter) ([]*models.AuditEntry, error) {
+	return f.Entries, nil
+}
+
+func (f *AuditStore) DeleteExpired(context.Context, time.Time, int) (int64, error) {
+	return 0, nil
+}
+
+func NewAuditService(store *AuditStore) *audit.Service {
+	return audit.NewAuditService(store, zap.NewNop().Sugar())
+}
+
+// UserStore ищет пользователей по логину. Как и хранилище, для неизвестного логина возвращает пустого пользователя.
+type UserStore struct {
+	interfaces.UserStore
+	Users map[string]*models.User
+}
+
+func NewUserStore(users ...*models.User) *UserStore {
+	store := &UserStore{Users: make(map[string]*models.User, len(users))}
+	for _, user := range users {
+		store.Users[user.Login] = user
+	}
+
+	return store
+}
+
+func (f *UserStore) GetUser(_ context.Context, login string) (*models.User, error) {
+	if user, ok := f.Users[login]; ok {
+		return user, nil
+	}
+
+	return &models.User{}, nil
+}
+
+func (f *UserStore) GetTxUser(ctx context.Context, _ pgx.Tx, login string) (*models.User, error) {
+	return f.GetUser(ctx, login)
+}
@@ -0,0 +1,117 @@
+package tier
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/audit"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/service/models"
+	storeModel "github.com/dontagr/loyalty/internal/store/models"
+)
+
+const defaultTierBatch = 1000
+
+type Service struct {
+	store        interfaces.TierStore
+	auditService *audit.Service
+	loyalty      *config.Loyalty
+	batch        int
+	log          *zap.SugaredLogger
+}
+
+func NewTierService(cfg *config.Config, store interfaces.TierStore, auditService *audit.Service, log *zap.SugaredLogger) *Service {
+	batch := cfg.Loyalty.TierBatch
+	if batch <= 0 {
+		batch = defaultTierBatch
+	}
+
+	return &Service{
+		store:        store,
+		auditService: auditService,
+		loyalty:      &cfg.Loyalty,
+		batch:        batch,
+		log:          log,
+	}
+}
+
+// GetTier возвращает действующий уровень пользователя и прогресс до следующего.
+// nil - уровни не настроены.
+func (t *Service) GetTier(ctx context.Context, userID int) (*models.ResponseTier, error) {
+	current := t.loyalty.Tier("")
+	if current == nil {
+		return nil, nil
+	}
+
+	userTier, err := t.store.GetUserTier(ctx, userID, periodStart(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	current = t.loyalty.Tier(userTier.Tier)
+	response := &models.ResponseTier{
+		Name:       current.Name,
+		Multiplier: current.Multiplier,
+		Accrued:    float64(userTier.Accrued) / 100,
+	}
+	if next := t.loyalty.NextTier(current); next != nil {
+		response.Next = next.Name
+		response.ToNext = math.Max(0, math.Round((next.Threshold-response.Accrued)*100)/100)
+	}
+
+	return response, nil
+}
+
+// Reevaluate пересчитывает уровни всех пользователей по начислениям за последние 12 месяцев,
+// повышая и понижая их. Возвращает число пользователей, у которых уровень изменился.
+func (t *Service) Reevaluate(ctx context.Context) (int, error) {
+	base := t.loyalty.Tier("")
+	if base == nil {
+		return 0, nil
+	}
+
+	since := periodStart(time.Now())
+	changed, afterID := 0, 0
+	for {
+		list, err := t.store.GetList(ctx, afterID, since, t.batch)
+		if err != nil {
+			return changed, err
+		}
+
+		for _, userTier := range list {
+			afterID = userTier.UserID
+
+			tier := t.loyalty.TierFor(float64(userTier.Accrued) / 100)
+			// пользователь без уровня и так получает начальный, сохранять его незачем
+			if tier.Name == userTier.Tier || (userTier.Tier == "" && tier == base) {
+				continue
+			}
+
+			updated, err := t.store.UpdateTier(ctx, userTier.UserID, tier.Name)
+			if err != nil {
+				t.log.Errorf("failed update tier of user %d: %v", userTier.UserID, err)
+				continue
+			}
+			if !updated {
+				continue
+			}
+
+			changed++
+			details, _ := json.Marshal(map[string]string{"from": userTier.Tier, "to": tier.Name})
+			t.auditService.Record(ctx, &storeModel.AuditEntry{Action: audit.ActionTierChange, Target: strconv.Itoa(userTier.UserID), Amount: &userTier.Accrued, Details: details})
+		}
+		if len(list) < t.batch {
+			return changed, nil
+		}
+	}
+}
+
+func periodStart(now time.Time) time.Time {
+	return now.AddDate(-1, 0, 0)
+}
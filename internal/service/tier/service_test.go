@@ -0,0 +1,149 @@
+package tier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/models"
+	"github.com/dontagr/loyalty/internal/service/servicetest"
+	storeModel "github.com/dontagr/loyalty/internal/store/models"
+)
+
+var testTiers = []config.Tier{
+	{Name: "GOLD", Threshold: 20000, Multiplier: 1.25},
+	{Name: "BASE", Threshold: 0, Multiplier: 1},
+	{Name: "SILVER", Threshold: 5000, Multiplier: 1.1},
+}
+
+type fakeTierStore struct {
+	users []*storeModel.UserTier
+}
+
+func (f *fakeTierStore) GetUserTier(_ context.Context, userID int, _ time.Time) (*storeModel.UserTier, error) {
+	for _, user := range f.users {
+		if user.UserID == userID {
+			return user, nil
+		}
+	}
+
+	return &storeModel.UserTier{}, nil
+}
+
+func (f *fakeTierStore) GetList(_ context.Context, afterID int, _ time.Time, limit int) ([]*storeModel.UserTier, error) {
+	var result []*storeModel.UserTier
+	for _, user := range f.users {
+		if user.UserID > afterID && len(result) < limit {
+			result = append(result, &storeModel.UserTier{UserID: user.UserID, Tier: user.Tier, Accrued: user.Accrued})
+		}
+	}
+
+	return result, nil
+}
+
+func (f *fakeTierStore) UpdateTier(_ context.Context, userID int, tier string) (bool, error) {
+	for _, user := range f.users {
+		if user.UserID == userID {
+			user.Tier = tier
+		}
+	}
+
+	return true, nil
+}
+
+func newTestService(tiers []config.Tier, store *fakeTierStore, auditStore *servicetest.AuditStore) *Service {
+	cfg := &config.Config{Loyalty: config.Loyalty{Tiers: tiers, TierBatch: 2}}
+
+	return NewTierService(cfg, store, servicetest.NewAuditService(auditStore), zap.NewNop().Sugar())
+}
+
+func TestReevaluate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tier    string
+		accrued int
+		want    string
+		details string
+	}{
+		{name: "base is not stored", accrued: 100, want: ""},
+		{name: "promote to silver", accrued: 600000, want: "SILVER", details: `{"from":"","to":"SILVER"}`},
+		{name: "demote gold", tier: "GOLD", accrued: 1000000, want: "SILVER", details: `{"from":"GOLD","to":"SILVER"}`},
+		{name: "keep gold", tier: "GOLD", accrued: 2500000, want: "GOLD"},
+		{name: "removed tier is logged as stored", tier: "REMOVED", want: "BASE", details: `{"from":"REMOVED","to":"BASE"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeTierStore{users: []*storeModel.UserTier{{UserID: 1, Tier: tt.tier, Accrued: tt.accrued}}}
+			auditStore := &servicetest.AuditStore{}
+
+			changed, err := newTestService(testTiers, store, auditStore).Reevaluate(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, store.users[0].Tier)
+			if tt.details == "" {
+				assert.Zero(t, changed)
+				assert.Empty(t, auditStore.Entries)
+				return
+			}
+			assert.Equal(t, 1, changed)
+			require.Len(t, auditStore.Entries, 1)
+			assert.JSONEq(t, tt.details, string(auditStore.Entries[0].Details))
+		})
+	}
+
+	t.Run("all batches", func(t *testing.T) {
+		store := &fakeTierStore{}
+		for i, tt := range tests {
+			store.users = append(store.users, &storeModel.UserTier{UserID: i + 1, Tier: tt.tier, Accrued: tt.accrued})
+		}
+
+		changed, err := newTestService(testTiers, store, &servicetest.AuditStore{}).Reevaluate(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, changed)
+		for i, tt := range tests {
+			assert.Equal(t, tt.want, store.users[i].Tier, tt.name)
+		}
+	})
+}
+
+func TestGetTier(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiers []config.Tier
+		user  *storeModel.UserTier
+		want  *models.ResponseTier
+	}{
+		{
+			name:  "progress to next tier",
+			tiers: testTiers,
+			user:  &storeModel.UserTier{UserID: 1, Accrued: 123456},
+			want:  &models.ResponseTier{Name: "BASE", Multiplier: 1, Accrued: 1234.56, Next: "SILVER", ToNext: 3765.44},
+		},
+		{
+			name:  "top tier",
+			tiers: testTiers,
+			user:  &storeModel.UserTier{UserID: 1, Tier: "GOLD", Accrued: 100},
+			want:  &models.ResponseTier{Name: "GOLD", Multiplier: 1.25, Accrued: 1},
+		},
+		{
+			name: "tiers not configured",
+			user: &storeModel.UserTier{UserID: 1, Accrued: 123456},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeTierStore{users: []*storeModel.UserTier{tt.user}}
+
+			tier, err := newTestService(tt.tiers, store, &servicetest.AuditStore{}).GetTier(context.Background(), tt.user.UserID)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tier)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/audit"
+	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/service/models"
+	storeModels "github.com/dontagr/loyalty/internal/store/models"
+)
+
+const limitWindow = 24 * time.Hour
+
+var errTransferRejected = errors.New("перевод отклонен")
+
+type Service struct {
+	store        interfaces.TransferStore
+	users        interfaces.UserStore
+	auditService *audit.Service
+	dailyAmount  int
+	dailyCount   int
+}
+
+func NewTransferService(cfg *config.Config, store interfaces.TransferStore, users interfaces.UserStore, auditService *audit.Service) *Service {
+	return &Service{
+		store:        store,
+		users:        users,
+		auditService: auditService,
+		dailyAmount:  int(math.Round(cfg.Transfer.DailyAmount * 100)),
+		dailyCount:   cfg.Transfer.DailyCount,
+	}
+}
+
+func (t *Service) Transfer(ctx context.Context, sender *storeModels.User, request *models.RequestTransfer) (*models.ResponseTransfer, *customerror.CustomError) {
+	amount := int(math.Round(request.Sum * 100))
+	if amount <= 0 {
+		return nil, customerror.NewCustomError(customerror.BadRequest, "Неверный формат запроса", nil)
+	}
+	if request.Login == sender.Login {
+		return nil, customerror.NewCustomError(customerror.Unprocessable, "Нельзя перевести баллы самому себе", nil)
+	}
+
+	recipient, err := t.users.GetUser(ctx, request.Login)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get recipient: %w", err))
+	}
+	if recipient.Login == "" {
+		return nil, customerror.NewCustomError(customerror.NotFound, "Получатель не найден", nil)
+	}
+	if recipient.BlockedAt != nil {
+		return nil, customerror.NewCustomError(customerror.Unprocessable, "Получатель не может принимать переводы", nil)
+	}
+
+	var cError *customerror.CustomError
+	transfer := &storeModels.Transfer{FromUserID: sender.ID, ToUserID: recipient.ID, ToLogin: recipient.Login, Amount: amount}
+	err = t.store.RunInTx(ctx, func(tx pgx.Tx) error {
+		cError = nil
+		from, err := t.lockUsers(ctx, tx, sender, recipient)
+		if err != nil {
+			return err
+		}
+		if from.Balance < amount {
+			cError = customerror.NewCustomError(customerror.Payment, "На счету недостаточно средств", nil)
+			return errTransferRejected
+		}
+
+		sent, count, err := t.store.GetSentTx(ctx, tx, sender.ID, time.Now().Add(-limitWindow))
+		if err != nil {
+			return err
+		}
+		if (t.dailyCount > 0 && count >= t.dailyCount) || (t.dailyAmount > 0 && sent+amount > t.dailyAmount) {
+			cError = customerror.NewCustomError(customerror.Unprocessable, "Превышен дневной лимит переводов", nil)
+			return errTransferRejected
+		}
+
+		err = t.store.SaveTx(ctx, tx, transfer)
+		if err != nil {
+			return err
+		}
+		err = t.recordTx(ctx, tx, sender, audit.ActionTransferSend, sender.Login, transfer, map[string]any{"to": recipient.Login})
+		if err != nil {
+			return err
+		}
+
+		return t.recordTx(ctx, tx, sender, audit.ActionTransferReceive, recipient.Login, transfer, map[string]any{"from": sender.Login})
+	})
+	if cError != nil {
+		return nil, cError
+	}
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
+	}
+
+	return &models.ResponseTransfer{
+		ID:             transfer.ID,
+		To:             recipient.Login,
+		Sum:            float64(amount) / 100,
+		CreateDateTime: transfer.CreateDateTime,
+	}, nil
+}
+
+// lockUsers блокирует строки обоих пользователей в порядке id, иначе встречные переводы
+// A->B и B->A могут взаимно заблокироваться. Возвращает заблокированного отправителя.
+func (t *Service) lockUsers(ctx context.Context, tx pgx.Tx, sender *storeModels.User, recipient *storeModels.User) (*storeModels.User, error) {
+	logins := []string{sender.Login, recipient.Login}
+	if recipient.ID < sender.ID {
+		logins[0], logins[1] = logins[1], logins[0]
+	}
+
+	var from *storeModels.User
+	for _, login := range logins {
+		user, err := t.users.GetTxUser(ctx, tx, login)
+		if err != nil {
+			return nil, fmt.Errorf("failed lock user %s: %w", login, err)
+		}
+		if user.Login == "" {
+			return nil, fmt.Errorf("user %s not found", login)
+		}
+		if user.ID == sender.ID {
+			from = user
+		}
+	}
+
+	return from, nil
+}
+
+func (t *Service) recordTx(ctx context.Context, tx pgx.Tx, sender *storeModels.User, action string, target string, transfer *storeModels.Transfer, details map[string]any) error {
+	details["transfer_id"] = transfer.ID
+	payload, _ := json.Marshal(details)
+
+	return t.auditService.RecordTx(ctx, tx, &storeModels.AuditEntry{ActorID: &sender.ID, Action: action, Target: target, Amount: &transfer.Amount, Details: payload})
+}
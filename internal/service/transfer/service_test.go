@@ -0,0 +1,85 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/models"
+	"github.com/dontagr/loyalty/internal/service/servicetest"
+	storeModels "github.com/dontagr/loyalty/internal/store/models"
+)
+
+type fakeTransferStore struct {
+	sentAmount int
+	sentCount  int
+	saved      []*storeModels.Transfer
+}
+
+func (f *fakeTransferStore) RunInTx(_ context.Context, fn func(tx pgx.Tx) error) error {
+	return fn(nil)
+}
+
+func (f *fakeTransferStore) GetSentTx(context.Context, pgx.Tx, int, time.Time) (int, int, error) {
+	return f.sentAmount, f.sentCount, nil
+}
+
+func (f *fakeTransferStore) SaveTx(_ context.Context, _ pgx.Tx, transfer *storeModels.Transfer) error {
+	f.saved = append(f.saved, transfer)
+	return nil
+}
+
+func TestTransfer(t *testing.T) {
+	tests := []struct {
+		name       string
+		login      string
+		sum        float64
+		sentAmount int
+		sentCount  int
+		code       int
+	}{
+		{name: "amount up to daily limit", login: "recipient", sum: 100, sentAmount: 90000, sentCount: 1},
+		{name: "amount over daily limit", login: "recipient", sum: 100.01, sentAmount: 90000, sentCount: 1, code: customerror.Unprocessable},
+		{name: "daily count reached", login: "recipient", sum: 1, sentCount: 3, code: customerror.Unprocessable},
+		{name: "transfer to self", login: "sender", sum: 1, code: customerror.Unprocessable},
+		{name: "blocked recipient", login: "blocked", sum: 1, code: customerror.Unprocessable},
+		{name: "unknown recipient", login: "nobody", sum: 1, code: customerror.NotFound},
+		{name: "insufficient funds", login: "recipient", sum: 10000.01, code: customerror.Payment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockedAt := time.Now()
+			sender := &storeModels.User{ID: 1, Login: "sender", Balance: 1000000}
+			users := servicetest.NewUserStore(
+				sender,
+				&storeModels.User{ID: 2, Login: "recipient"},
+				&storeModels.User{ID: 3, Login: "blocked", BlockedAt: &blockedAt},
+			)
+			store := &fakeTransferStore{sentAmount: tt.sentAmount, sentCount: tt.sentCount}
+			auditStore := &servicetest.AuditStore{}
+			cfg := &config.Config{Transfer: config.Transfer{DailyAmount: 1000, DailyCount: 3}}
+			service := NewTransferService(cfg, store, users, servicetest.NewAuditService(auditStore))
+
+			response, cError := service.Transfer(context.Background(), sender, &models.RequestTransfer{Login: tt.login, Sum: tt.sum})
+			if tt.code != 0 {
+				require.NotNil(t, cError)
+				assert.Equal(t, tt.code, cError.Code)
+				assert.Empty(t, store.saved)
+				assert.Empty(t, auditStore.Entries)
+				return
+			}
+			require.Nil(t, cError)
+			assert.Equal(t, tt.login, response.To)
+			assert.Equal(t, tt.sum, response.Sum)
+			require.Len(t, store.saved, 1)
+			assert.Equal(t, int(tt.sum*100), store.saved[0].Amount)
+			assert.Len(t, auditStore.Entries, 2)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,38 +10,68 @@ import (
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/metrics"
 	"github.com/dontagr/loyalty/internal/service/customerror"
 	"github.com/dontagr/loyalty/internal/service/transport/models"
+	"github.com/dontagr/loyalty/internal/tracing"
 )
 
+var tracer = otel.Tracer("github.com/dontagr/loyalty/internal/service/transport")
+
 type HTTPManager struct {
 	urlPattern string
 	client     *http.Client
+	limiter    *adaptiveLimiter
+	metrics    *metrics.Metrics
 	log        *zap.SugaredLogger
 	cfg        *config.Config
 }
 
-func NewHTTPManager(cfg *config.Config, log *zap.SugaredLogger) *HTTPManager {
-	return &HTTPManager{urlPattern: "%s/api/orders/%s", log: log, client: &http.Client{}, cfg: cfg}
+func NewHTTPManager(cfg *config.Config, log *zap.SugaredLogger, m *metrics.Metrics) *HTTPManager {
+	return &HTTPManager{
+		urlPattern: "%s/api/orders/%s",
+		log:        log,
+		client:     &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		limiter:    newAdaptiveLimiter(cfg.Service),
+		metrics:    m,
+		cfg:        cfg,
+	}
+}
+
+func (h *HTTPManager) NewRequest(ctx context.Context, orderID string, w int) (*models.OrderResponse, *customerror.CustomError) {
+	ctx, span := tracer.Start(ctx, "accrual.NewRequest", trace.WithAttributes(attribute.String("order.id", orderID), attribute.Int("worker", w)))
+	defer span.End()
+
+	response, cError := h.newRequest(ctx, orderID, w)
+	if cError != nil {
+		span.SetAttributes(attribute.Int("accrual.error_code", cError.Code))
+		tracing.RecordError(span, cError)
+	}
+
+	return response, cError
 }
 
-func (h *HTTPManager) NewRequest(orderID string, w int) (*models.OrderResponse, *customerror.CustomError) {
-	req, err := http.NewRequest("GET", fmt.Sprintf(h.urlPattern, h.cfg.CalculateSystem.URI, orderID), nil)
+func (h *HTTPManager) newRequest(ctx context.Context, orderID string, w int) (*models.OrderResponse, *customerror.CustomError) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(h.urlPattern, h.cfg.CalculateSystem.URI, orderID), nil)
 	if err != nil {
 		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("creating request: %v", err))
 	}
 
-	h.log.Infof("url for sending %s", fmt.Sprintf(h.urlPattern, h.cfg.CalculateSystem.URI, orderID))
+	h.log.Debugf("url for sending %s", fmt.Sprintf(h.urlPattern, h.cfg.CalculateSystem.URI, orderID))
 
 	var resp *http.Response
 	var netErr *net.OpError
 	var errSend error
 	var orderResponse *models.OrderResponse
 	for i := 0; i < 3; i++ {
-		resp, errSend = h.client.Do(req)
+		resp, errSend = h.do(req)
 		if errSend == nil {
 			defer func(Body io.ReadCloser, w int) {
 				err := Body.Close()
@@ -65,9 +96,9 @@ func (h *HTTPManager) NewRequest(orderID string, w int) (*models.OrderResponse,
 				h.log.Errorf("worker %d failed close body %v", w, err)
 			}
 		}
-		if errors.As(errSend, &netErr) {
+		// паузу перед повтором выдерживает лимитер: сетевая ошибка снижает его частоту запросов
+		if errors.As(errSend, &netErr) && ctx.Err() == nil {
 			h.log.Warnf("worker %d connection error we try №%d", w, i+1)
-			time.Sleep(5 * time.Second)
 		} else {
 			return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("sending data: %v", errSend))
 		}
@@ -77,7 +108,47 @@ func (h *HTTPManager) NewRequest(orderID string, w int) (*models.OrderResponse,
 		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("sending data: %v", errSend))
 	}
 
-	h.log.Infof("worker %d request success full", w)
+	h.log.Debugf("worker %d request success full", w)
 
 	return orderResponse, nil
 }
+
+// Ping проверяет, что система расчета отвечает. Любой HTTP ответ считается успехом, лимитер не задействуется,
+// чтобы проверки готовности не расходовали квоту опроса заказов.
+func (h *HTTPManager) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.cfg.CalculateSystem.URI, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending data: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return resp.Body.Close()
+}
+
+func (h *HTTPManager) do(req *http.Request) (*http.Response, error) {
+	if err := h.limiter.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := h.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		if req.Context().Err() != nil {
+			h.limiter.cancel()
+		} else {
+			h.metrics.ObserveAccrualRequest(0, latency)
+			h.limiter.release(0, "", latency)
+		}
+
+		return nil, err
+	}
+	h.metrics.ObserveAccrualRequest(resp.StatusCode, latency)
+	h.limiter.release(resp.StatusCode, resp.Header.Get("Retry-After"), latency)
+
+	return resp, nil
+}
@@ -1,12 +1,14 @@
 package transport
 
 import (
+	"context"
+
 	error2 "github.com/dontagr/loyalty/internal/service/customerror"
 	"github.com/dontagr/loyalty/internal/service/transport/models"
 )
 
 type (
 	Transport interface {
-		NewRequest(orderID string, w int) (*models.OrderResponse, *error2.CustomError)
+		NewRequest(ctx context.Context, orderID string, w int) (*models.OrderResponse, *error2.CustomError)
 	}
 )
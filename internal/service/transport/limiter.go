@@ -0,0 +1,199 @@
+package transport
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dontagr/loyalty/internal/config"
+)
+
+const (
+	defaultMinRate        = 1
+	defaultMaxRate        = 100
+	defaultRateIncrease   = 1
+	defaultDecreaseFactor = 0.5
+	defaultLatencyTarget  = time.Second
+	defaultRetryAfter     = 60 * time.Second
+)
+
+// adaptiveLimiter общий для всех воркеров: ограничивает число одновременных запросов и их частоту,
+// увеличивая оба лимита аддитивно при успешных ответах и уменьшая мультипликативно при 429 или росте задержки.
+type adaptiveLimiter struct {
+	mu             sync.Mutex
+	released       chan struct{}
+	inFlight       int
+	limit          float64
+	maxLimit       float64
+	rate           float64
+	minRate        float64
+	maxRate        float64
+	rateIncrease   float64
+	decreaseFactor float64
+	latencyTarget  time.Duration
+	retryAfter     time.Duration
+	next           time.Time
+	pausedUntil    time.Time
+	lastDecrease   time.Time
+	now            func() time.Time
+	sleep          func(context.Context, time.Duration) error
+}
+
+func newAdaptiveLimiter(cfg config.Service) *adaptiveLimiter {
+	maxLimit := cfg.AccrualMaxConcurrency
+	if maxLimit <= 0 {
+		maxLimit = max(cfg.WorkerLimit, 1)
+	}
+	minRate := floatOrDefault(cfg.AccrualMinRate, defaultMinRate)
+	maxRate := math.Max(floatOrDefault(cfg.AccrualMaxRate, defaultMaxRate), minRate)
+	decreaseFactor := floatOrDefault(cfg.AccrualDecreaseFactor, defaultDecreaseFactor)
+	if decreaseFactor >= 1 {
+		decreaseFactor = defaultDecreaseFactor
+	}
+
+	l := &adaptiveLimiter{
+		limit:          float64(maxLimit),
+		maxLimit:       float64(maxLimit),
+		rate:           maxRate,
+		minRate:        minRate,
+		maxRate:        maxRate,
+		rateIncrease:   floatOrDefault(cfg.AccrualRateIncrease, defaultRateIncrease),
+		decreaseFactor: decreaseFactor,
+		latencyTarget:  durationOrDefault(cfg.AccrualLatencyTarget, time.Millisecond, defaultLatencyTarget),
+		retryAfter:     durationOrDefault(cfg.AccrualRetryAfter, time.Second, defaultRetryAfter),
+		released:       make(chan struct{}),
+		now:            time.Now,
+		sleep:          sleepContext,
+	}
+
+	return l
+}
+
+func (l *adaptiveLimiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	for float64(l.inFlight) >= math.Floor(l.limit) {
+		released := l.released
+		l.mu.Unlock()
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	l.inFlight++
+	l.mu.Unlock()
+
+	for {
+		l.mu.Lock()
+		now := l.now()
+		start := now
+		if l.next.After(start) {
+			start = l.next
+		}
+		if l.pausedUntil.After(start) {
+			start = l.pausedUntil
+		}
+		if !start.After(now) {
+			l.next = now.Add(time.Duration(float64(time.Second) / l.rate))
+			l.mu.Unlock()
+
+			return nil
+		}
+		l.mu.Unlock()
+
+		if err := l.sleep(ctx, start.Sub(now)); err != nil {
+			l.cancel()
+
+			return err
+		}
+	}
+}
+
+func (l *adaptiveLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.notify()
+}
+
+// release возвращает слот и подстраивает лимиты по результату запроса; status 0 означает сетевую ошибку.
+func (l *adaptiveLimiter) release(status int, retryAfter string, latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	now := l.now()
+	switch {
+	case status == http.StatusTooManyRequests:
+		until := now.Add(l.parseRetryAfter(retryAfter, now))
+		if until.After(l.pausedUntil) {
+			l.pausedUntil = until
+		}
+		l.decrease(now)
+	case status == 0 || status >= http.StatusInternalServerError || latency > l.latencyTarget:
+		l.decrease(now)
+	default:
+		l.limit = math.Min(l.limit+1/l.limit, l.maxLimit)
+		l.rate = math.Min(l.rate+l.rateIncrease, l.maxRate)
+	}
+	l.notify()
+}
+
+func (l *adaptiveLimiter) notify() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// decrease срабатывает не чаще раза за целевую задержку, чтобы пачка одновременных 429 не обнулила лимиты.
+func (l *adaptiveLimiter) decrease(now time.Time) {
+	if now.Sub(l.lastDecrease) < l.latencyTarget {
+		return
+	}
+	l.lastDecrease = now
+	l.limit = math.Max(l.limit*l.decreaseFactor, 1)
+	l.rate = math.Max(l.rate*l.decreaseFactor, l.minRate)
+}
+
+func (l *adaptiveLimiter) parseRetryAfter(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return l.retryAfter
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func floatOrDefault(value float64, def float64) float64 {
+	if value <= 0 {
+		return def
+	}
+
+	return value
+}
+
+func durationOrDefault(value int, unit time.Duration, def time.Duration) time.Duration {
+	if value <= 0 {
+		return def
+	}
+
+	return time.Duration(value) * unit
+}
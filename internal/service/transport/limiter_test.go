@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dontagr/loyalty/internal/config"
+)
+
+func newTestLimiter(now *time.Time) *adaptiveLimiter {
+	l := newAdaptiveLimiter(config.Service{
+		WorkerLimit:           4,
+		AccrualMinRate:        1,
+		AccrualMaxRate:        10,
+		AccrualRateIncrease:   1,
+		AccrualDecreaseFactor: 0.5,
+		AccrualLatencyTarget:  500,
+		AccrualRetryAfter:     60,
+	})
+	l.now = func() time.Time { return *now }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		*now = now.Add(d)
+		return nil
+	}
+
+	return l
+}
+
+func TestAdaptiveLimiterTooManyRequests(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	assert.NoError(t, l.acquire(context.Background()))
+	assert.NoError(t, l.acquire(context.Background()))
+	l.release(http.StatusTooManyRequests, "30", 10*time.Millisecond)
+	l.release(http.StatusTooManyRequests, "", 10*time.Millisecond)
+
+	assert.Equal(t, now.Add(60*time.Second), l.pausedUntil)
+	assert.Equal(t, 2.0, l.limit)
+	assert.Equal(t, 5.0, l.rate)
+	assert.Zero(t, l.inFlight)
+}
+
+func TestAdaptiveLimiterRetryAfterDate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	assert.NoError(t, l.acquire(context.Background()))
+	l.release(http.StatusTooManyRequests, now.Add(2*time.Minute).Format(http.TimeFormat), 0)
+
+	assert.Equal(t, now.Add(2*time.Minute), l.pausedUntil)
+}
+
+func TestAdaptiveLimiterRecovers(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	assert.NoError(t, l.acquire(context.Background()))
+	l.release(http.StatusOK, "", 2*time.Second)
+	assert.Equal(t, 2.0, l.limit)
+	assert.Equal(t, 5.0, l.rate)
+
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		assert.NoError(t, l.acquire(context.Background()))
+		l.release(http.StatusOK, "", 10*time.Millisecond)
+	}
+	assert.Equal(t, 10.0, l.rate)
+	assert.Greater(t, l.limit, 3.0)
+	assert.LessOrEqual(t, l.limit, 4.0)
+}
+
+func TestAdaptiveLimiterAcquireCancelled(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+	l.limit = 1
+
+	assert.NoError(t, l.acquire(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.acquire(ctx), context.Canceled)
+	assert.Equal(t, 1, l.inFlight)
+}
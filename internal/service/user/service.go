@@ -1,11 +1,14 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/dontagr/loyalty/internal/service/audit"
 	"github.com/dontagr/loyalty/internal/service/customerror"
 	"github.com/dontagr/loyalty/internal/service/interfaces"
 	"github.com/dontagr/loyalty/internal/service/jwt"
@@ -13,16 +16,17 @@ import (
 )
 
 type Service struct {
-	store      interfaces.UserStore
-	jwtService *jwt.JWTService
+	store        interfaces.UserStore
+	jwtService   *jwt.JWTService
+	auditService *audit.Service
 }
 
-func NewUserService(store interfaces.UserStore, jwtService *jwt.JWTService) *Service {
-	return &Service{store: store, jwtService: jwtService}
+func NewUserService(store interfaces.UserStore, jwtService *jwt.JWTService, auditService *audit.Service) *Service {
+	return &Service{store: store, jwtService: jwtService, auditService: auditService}
 }
 
-func (u *Service) HasLogin(login string) (bool, error) {
-	user, err := u.store.GetUser(login)
+func (u *Service) HasLogin(ctx context.Context, login string) (bool, error) {
+	user, err := u.store.GetUser(ctx, login)
 	if err != nil {
 		return false, err
 	}
@@ -30,56 +34,83 @@ func (u *Service) HasLogin(login string) (bool, error) {
 	return user.Login == login, nil
 }
 
-func (u *Service) GetTxUser(tx pgx.Tx, login string) (*models.User, error) {
-	return u.store.GetTxUser(tx, login)
+func (u *Service) GetTxUser(ctx context.Context, tx pgx.Tx, login string) (*models.User, error) {
+	return u.store.GetTxUser(ctx, tx, login)
 }
 
-func (u *Service) GetUser(login string) (*models.User, error) {
-	return u.store.GetUser(login)
+func (u *Service) GetUser(ctx context.Context, login string) (*models.User, error) {
+	return u.store.GetUser(ctx, login)
 }
 
-func (u *Service) SignUp(login string, password string) (string, error) {
+func (u *Service) SignUp(ctx context.Context, login string, password string) (*jwt.Tokens, error) {
 	passHash, err := u.generatePassHash(password)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = u.store.SaveUser(login, passHash)
+	err = u.store.SaveUser(ctx, login, passHash)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	user, err := u.store.GetUser(login)
+	user, err := u.store.GetUser(ctx, login)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	jwtHash, err := u.jwtService.GetJWT(user.ID, user.Login)
+	u.auditService.Record(ctx, &models.AuditEntry{ActorID: &user.ID, Action: audit.ActionSignUp, Target: user.Login})
+
+	tokens, err := u.jwtService.IssueTokens(ctx, user.ID, user.Login, user.Role)
 	if err != nil {
-		return "", fmt.Errorf("failed create jwt: %v", err)
+		return nil, fmt.Errorf("failed create jwt: %w", err)
 	}
 
-	return jwtHash, nil
+	return tokens, nil
 }
 
-func (u *Service) SignIn(password string, user *models.User) (string, *customerror.CustomError) {
+func (u *Service) SignIn(ctx context.Context, password string, user *models.User) (*jwt.Tokens, *customerror.CustomError) {
 	valid, cError := u.CompareHashAndPassword(user, password)
 	if !valid {
-		return "", cError
+		return nil, cError
+	}
+	// пароль проверяется раньше блокировки, чтобы по ответу нельзя было узнать о блокировке без пароля
+	if user.BlockedAt != nil {
+		u.auditService.Record(ctx, &models.AuditEntry{ActorID: &user.ID, Action: audit.ActionLoginFailed, Target: user.Login, Details: []byte(`{"reason":"blocked"}`)})
+
+		return nil, customerror.NewCustomError(customerror.Forbidden, "Пользователь заблокирован", nil)
+	}
+
+	tokens, err := u.jwtService.IssueTokens(ctx, user.ID, user.Login, user.Role)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed create jwt: %w", err))
 	}
+	u.auditService.Record(ctx, &models.AuditEntry{ActorID: &user.ID, Action: audit.ActionLogin, Target: user.Login})
 
-	jwtHash, err := u.jwtService.GetJWT(user.ID, user.Login)
+	return tokens, nil
+}
+
+// LoginFailed фиксирует неудачную попытку входа. Пользователь может не существовать,
+// поэтому исполнитель не указывается, а логин сохраняется как цель.
+func (u *Service) LoginFailed(ctx context.Context, login string) {
+	u.auditService.Record(ctx, &models.AuditEntry{Action: audit.ActionLoginFailed, Target: login})
+}
+
+func (u *Service) Refresh(ctx context.Context, refreshToken string) (*jwt.Tokens, *customerror.CustomError) {
+	tokens, err := u.jwtService.Refresh(ctx, refreshToken)
+	if errors.Is(err, models.ErrRefreshTokenInvalid) || errors.Is(err, models.ErrRefreshTokenReused) {
+		return nil, customerror.NewCustomError(customerror.Unauthorized, "Недействительный refresh-токен", err)
+	}
 	if err != nil {
-		return "", customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed create jwt: %v", err))
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed refresh jwt: %w", err))
 	}
 
-	return jwtHash, nil
+	return tokens, nil
 }
 
 func (u *Service) generatePassHash(password string) (string, error) {
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate password hash: %v", err)
+		return "", fmt.Errorf("failed to generate password hash: %w", err)
 	}
 
 	return string(passHash), nil
@@ -87,7 +118,7 @@ func (u *Service) generatePassHash(password string) (string, error) {
 
 func (u *Service) CompareHashAndPassword(user *models.User, password string) (bool, *customerror.CustomError) {
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return false, customerror.NewCustomError(customerror.Internal, "Неверная пара логин/пароль", nil)
+		return false, customerror.NewCustomError(customerror.Unauthorized, "Неверная пара логин/пароль", nil)
 	}
 
 	return true, nil
@@ -1,76 +1,312 @@
 package withdrawal
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/metrics"
+	"github.com/dontagr/loyalty/internal/service/audit"
 	"github.com/dontagr/loyalty/internal/service/customerror"
 	"github.com/dontagr/loyalty/internal/service/interfaces"
 	"github.com/dontagr/loyalty/internal/service/models"
+	"github.com/dontagr/loyalty/internal/service/pagination"
 	"github.com/dontagr/loyalty/internal/service/user"
 	storeModel "github.com/dontagr/loyalty/internal/store/models"
 )
 
+const (
+	SourceAdmin   = "admin"
+	SourcePartner = "partner"
+
+	withdrawAcceptedMessage  = "Запрос на снятие успешно обработан"
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+)
+
+var errWithdrawRejected = errors.New("списание отклонено")
+
 type Service struct {
-	store interfaces.WithdrawalStore
+	store        interfaces.WithdrawalStore
+	idempotency  interfaces.IdempotencyStore
+	keyTTL       time.Duration
+	metrics      *metrics.Metrics
+	auditService *audit.Service
 }
 
-func NewWithdrawalService(store interfaces.WithdrawalStore) *Service {
-	return &Service{store: store}
+func NewWithdrawalService(
+	cfg *config.Config,
+	store interfaces.WithdrawalStore,
+	idempotency interfaces.IdempotencyStore,
+	m *metrics.Metrics,
+	auditService *audit.Service,
+) *Service {
+	keyTTL := time.Duration(cfg.Service.IdempotencyKeyTTL) * time.Second
+	if keyTTL <= 0 {
+		keyTTL = defaultIdempotencyKeyTTL
+	}
+
+	return &Service{store: store, idempotency: idempotency, keyTTL: keyTTL, metrics: m, auditService: auditService}
 }
 
-func (w *Service) GetTotalWithdrawal(userID int) (float64, error) {
-	return w.store.GetTotalWithdrawal(userID)
+func (w *Service) GetTotalWithdrawal(ctx context.Context, userID int) (float64, error) {
+	return w.store.GetTotalWithdrawal(ctx, userID)
 }
 
-func (w *Service) SaveWithdraw(reqW *models.RequestWithdraw, userService *user.Service, login string) *customerror.CustomError {
-	tx, txErr := w.store.BeginTX()
-	if txErr != nil {
-		return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get userDTO %v", txErr))
-	}
-	defer func(txErr *error) {
-		if *txErr != nil {
-			w.store.RollbackTX(tx)
-		} else {
-			w.store.CommitTX(tx)
+// SaveWithdraw списывает баллы. С непустым idempotencyKey успешный ответ сохраняется в той же транзакции,
+// что и списание, и повтор с тем же ключом и телом возвращает его без повторного списания.
+// Отказы не сохраняются: баллы при них не двигались, и повтор безопасно проверит запрос заново.
+func (w *Service) SaveWithdraw(ctx context.Context, reqW *models.RequestWithdraw, userService *user.Service, login string, idempotencyKey string) (*models.IdempotentResponse, *customerror.CustomError) {
+	var cError *customerror.CustomError
+	var response *models.IdempotentResponse
+	sum := int(reqW.Sum * 100)
+	fingerprint := withdrawFingerprint(reqW.Order, sum)
+	err := w.store.RunInTx(ctx, func(tx pgx.Tx) error {
+		cError = nil
+		// блокировка строки пользователя упорядочивает и конкурентные запросы с одним ключом
+		userDTO, err := userService.GetTxUser(ctx, tx, login)
+		if err != nil {
+			return fmt.Errorf("failed get userDTO %w", err)
+		}
+
+		if idempotencyKey != "" {
+			record, err := w.idempotency.GetTx(ctx, tx, userDTO.ID, idempotencyKey, w.keyTTL)
+			if err != nil {
+				return err
+			}
+			if record != nil {
+				if record.Fingerprint != fingerprint {
+					cError = customerror.NewCustomError(customerror.Conflict, "Ключ идемпотентности уже использован для другого запроса", nil)
+					return errWithdrawRejected
+				}
+				response = &models.IdempotentResponse{StatusCode: record.StatusCode, Body: record.Response, Replayed: true}
+
+				return nil
+			}
+		}
+
+		if userDTO.Balance-sum < 0 {
+			cError = customerror.NewCustomError(customerror.Payment, "На счету недостаточно средств", nil)
+			return errWithdrawRejected
+		}
+
+		withdraw, err := w.store.GetWithdraw(ctx, reqW.Order)
+		if err != nil {
+			return err
+		}
+		if withdraw.ID != "" {
+			cError = customerror.NewCustomError(customerror.Unprocessable, "Неверный номер заказа", nil)
+			return errWithdrawRejected
+		}
+
+		err = w.store.SaveWithdraw(ctx, tx, storeModel.Withdrawal{ID: reqW.Order, Withdrawal: sum, UserID: userDTO.ID})
+		if err != nil {
+			return err
+		}
+		err = w.auditService.RecordTx(ctx, tx, &storeModel.AuditEntry{ActorID: &userDTO.ID, Action: audit.ActionWithdrawalCreate, Target: reqW.Order, Amount: &sum})
+		if err != nil {
+			return err
 		}
-	}(&txErr)
 
-	userDTO, err := userService.GetTxUser(tx, login)
+		body, err := json.Marshal(withdrawAcceptedMessage)
+		if err != nil {
+			return fmt.Errorf("failed marshal response: %w", err)
+		}
+		response = &models.IdempotentResponse{StatusCode: http.StatusOK, Body: body}
+		if idempotencyKey == "" {
+			return nil
+		}
+
+		return w.idempotency.SaveTx(ctx, tx, &storeModel.IdempotencyKey{
+			UserID:      userDTO.ID,
+			Key:         idempotencyKey,
+			Fingerprint: fingerprint,
+			StatusCode:  response.StatusCode,
+			Response:    body,
+		}, w.keyTTL)
+	})
+	if cError != nil {
+		return nil, cError
+	}
 	if err != nil {
-		txErr = err
-		return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get userDTO %v", err))
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
 	}
+	if response.Replayed {
+		return response, nil
+	}
+	w.metrics.PointsWithdrawn(sum)
 
-	sum := int(reqW.Sum * 100)
-	if userDTO.Balance-sum < 0 {
-		txErr = fmt.Errorf("на счету недостаточно средств")
-		return customerror.NewCustomError(customerror.Payment, "На счету недостаточно средств", nil)
+	return response, nil
+}
+
+// CompleteWithdraw подтверждает списание по обратному вызову партнера. Повторное подтверждение
+// не считается ошибкой, а отмененное списание подтвердить нельзя.
+func (w *Service) CompleteWithdraw(ctx context.Context, orderID string) (*storeModel.Withdrawal, *customerror.CustomError) {
+	var cError *customerror.CustomError
+	var withdrawal *storeModel.Withdrawal
+	err := w.store.RunInTx(ctx, func(tx pgx.Tx) error {
+		cError = nil
+		var err error
+		withdrawal, cError, err = w.findTxWithdraw(ctx, tx, orderID)
+		if cError != nil || err != nil {
+			return err
+		}
+
+		switch withdrawal.Status {
+		case storeModel.WithdrawalCompleted:
+			return nil
+		case storeModel.WithdrawalReversed:
+			cError = customerror.NewCustomError(customerror.Conflict, "Списание уже отменено", nil)
+			return nil
+		}
+		withdrawal.Status = storeModel.WithdrawalCompleted
+		err = w.store.UpdateStatusTx(ctx, tx, withdrawal)
+		if err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(map[string]string{"source": SourcePartner})
+		return w.auditService.RecordTx(ctx, tx, &storeModel.AuditEntry{Action: audit.ActionWithdrawalComplete, Target: orderID, Details: details})
+	})
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
 	}
+	if cError != nil {
+		return nil, cError
+	}
+
+	return withdrawal, nil
+}
 
-	withdraw, err := w.store.GetWithdraw(reqW.Order)
+// ReverseWithdraw отменяет списание и возвращает баллы в одной транзакции. Повторная отмена не ошибка
+// и баллы второй раз не возвращает, поэтому партнер может безопасно повторять обратный вызов.
+func (w *Service) ReverseWithdraw(ctx context.Context, orderID string, reason string, source string, actorID *int) (*storeModel.Withdrawal, *customerror.CustomError) {
+	var cError *customerror.CustomError
+	var withdrawal *storeModel.Withdrawal
+	err := w.store.RunInTx(ctx, func(tx pgx.Tx) error {
+		cError = nil
+		var err error
+		withdrawal, cError, err = w.findTxWithdraw(ctx, tx, orderID)
+		if cError != nil || err != nil {
+			return err
+		}
+		if withdrawal.Status == storeModel.WithdrawalReversed {
+			return nil
+		}
+		if reason != "" {
+			withdrawal.ReversalReason = &reason
+		}
+		err = w.store.ReverseTx(ctx, tx, withdrawal)
+		if err != nil {
+			return err
+		}
+
+		details, _ := json.Marshal(map[string]string{"source": source, "reason": reason})
+		return w.auditService.RecordTx(ctx, tx, &storeModel.AuditEntry{
+			ActorID: actorID,
+			Action:  audit.ActionWithdrawalReverse,
+			Target:  orderID,
+			Amount:  &withdrawal.Withdrawal,
+			Details: details,
+		})
+	})
 	if err != nil {
-		txErr = err
-		return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
 	}
-	if withdraw.ID != "" {
-		txErr = fmt.Errorf("неверный номер заказа")
-		return customerror.NewCustomError(customerror.Unprocessable, "Неверный номер заказа", nil)
+	if cError != nil {
+		return nil, cError
 	}
 
-	err = w.store.SaveWithdraw(tx, storeModel.Withdrawal{ID: reqW.Order, Withdrawal: sum, UserID: userDTO.ID})
+	return withdrawal, nil
+}
+
+func (w *Service) findTxWithdraw(ctx context.Context, tx pgx.Tx, orderID string) (*storeModel.Withdrawal, *customerror.CustomError, error) {
+	withdrawal, err := w.store.GetTxWithdraw(ctx, tx, orderID)
 	if err != nil {
-		txErr = err
-		return customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", err)
+		return nil, nil, err
+	}
+	if withdrawal.ID == "" {
+		return nil, customerror.NewCustomError(customerror.NotFound, "Списание не найдено", nil), nil
 	}
 
-	return nil
+	return withdrawal, nil, nil
 }
 
-func (w *Service) GetListByUser(user *storeModel.User) ([]*storeModel.Withdrawal, *customerror.CustomError) {
-	list, err := w.store.GetWithdrawalListByUserID(user.ID)
+func (w *Service) GetListByUser(ctx context.Context, user *storeModel.User) ([]*storeModel.Withdrawal, *customerror.CustomError) {
+	list, err := w.store.GetWithdrawalListByUserID(ctx, user.ID)
 	if err != nil {
-		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get list order: %v", err))
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get list order: %w", err))
 	}
 
 	return list, nil
 }
+
+func (w *Service) GetPageByUser(ctx context.Context, user *storeModel.User, request *models.RequestWithdrawalList) (*models.ResponseWithdrawalList, *customerror.CustomError) {
+	filter, err := w.buildFilter(request)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.BadRequest, "Неверный формат запроса", err)
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	list, err := w.store.GetWithdrawalPageByUserID(ctx, user.ID, filter)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get list withdrawal: %w", err))
+	}
+
+	total, err := w.store.GetPeriodTotal(ctx, user.ID, filter.From, filter.To)
+	if err != nil {
+		return nil, customerror.NewCustomError(customerror.Internal, "Внутренняя ошибка сервера", fmt.Errorf("failed get period total: %w", err))
+	}
+
+	response := &models.ResponseWithdrawalList{Withdrawals: list, Total: float64(total) / 100}
+	if len(list) > limit {
+		response.Withdrawals = list[:limit]
+		last := response.Withdrawals[limit-1]
+		response.NextCursor = pagination.EncodeCursor(pagination.Cursor{Time: last.CreateDateTime, ID: last.ID})
+	}
+
+	return response, nil
+}
+
+func (w *Service) buildFilter(request *models.RequestWithdrawalList) (*storeModel.WithdrawalFilter, error) {
+	var err error
+	filter := &storeModel.WithdrawalFilter{}
+
+	filter.Limit, err = pagination.ParseLimit(request.Limit)
+	if err != nil {
+		return nil, err
+	}
+	filter.From, err = pagination.ParseTime(request.From)
+	if err != nil {
+		return nil, err
+	}
+	filter.To, err = pagination.ParseTime(request.To)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := pagination.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		filter.After = &storeModel.PageKey{Time: cursor.Time, ID: cursor.ID}
+	}
+
+	return filter, nil
+}
+
+// withdrawFingerprint определяет, тот же ли запрос повторяется под ключом идемпотентности.
+func withdrawFingerprint(order string, sum int) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", order, sum)))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,101 @@
+package withdrawal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/metrics"
+	"github.com/dontagr/loyalty/internal/service/customerror"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/service/models"
+	"github.com/dontagr/loyalty/internal/service/servicetest"
+	"github.com/dontagr/loyalty/internal/service/user"
+	storeModel "github.com/dontagr/loyalty/internal/store/models"
+)
+
+type fakeWithdrawalStore struct {
+	interfaces.WithdrawalStore
+	saved []storeModel.Withdrawal
+}
+
+func (f *fakeWithdrawalStore) RunInTx(_ context.Context, fn func(tx pgx.Tx) error) error {
+	return fn(nil)
+}
+
+func (f *fakeWithdrawalStore) GetWithdraw(_ context.Context, orderID string) (*storeModel.Withdrawal, error) {
+	for _, withdrawal := range f.saved {
+		if withdrawal.ID == orderID {
+			return &withdrawal, nil
+		}
+	}
+
+	return &storeModel.Withdrawal{}, nil
+}
+
+func (f *fakeWithdrawalStore) SaveWithdraw(_ context.Context, _ pgx.Tx, withdrawal storeModel.Withdrawal) error {
+	f.saved = append(f.saved, withdrawal)
+	return nil
+}
+
+type fakeIdempotencyStore struct {
+	keys map[string]*storeModel.IdempotencyKey
+}
+
+func (f *fakeIdempotencyStore) GetTx(_ context.Context, _ pgx.Tx, _ int, key string, _ time.Duration) (*storeModel.IdempotencyKey, error) {
+	return f.keys[key], nil
+}
+
+func (f *fakeIdempotencyStore) SaveTx(_ context.Context, _ pgx.Tx, key *storeModel.IdempotencyKey, _ time.Duration) error {
+	f.keys[key.Key] = key
+	return nil
+}
+
+func TestSaveWithdrawIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    string
+		sum      float64
+		key      string
+		code     int
+		replayed bool
+		saved    int
+	}{
+		{name: "replay with same key and body", order: "2377225624", sum: 100, key: "key", replayed: true, saved: 1},
+		{name: "same key with other sum", order: "2377225624", sum: 200, key: "key", code: customerror.Conflict, saved: 1},
+		{name: "same key with other order", order: "49927398716", sum: 100, key: "key", code: customerror.Conflict, saved: 1},
+		{name: "new key for saved order", order: "2377225624", sum: 100, key: "other", code: customerror.Unprocessable, saved: 1},
+		{name: "new key for new order", order: "49927398716", sum: 100, key: "other", saved: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeWithdrawalStore{}
+			auditService := servicetest.NewAuditService(&servicetest.AuditStore{})
+			userService := user.NewUserService(servicetest.NewUserStore(&storeModel.User{ID: 1, Login: "user", Balance: 100000}), nil, auditService)
+			idempotency := &fakeIdempotencyStore{keys: map[string]*storeModel.IdempotencyKey{}}
+			service := NewWithdrawalService(&config.Config{}, store, idempotency, metrics.NewMetrics(nil), auditService)
+			ctx := context.Background()
+
+			first, cError := service.SaveWithdraw(ctx, &models.RequestWithdraw{Order: "2377225624", Sum: 100}, userService, "user", "key")
+			require.Nil(t, cError)
+			require.False(t, first.Replayed)
+
+			response, cError := service.SaveWithdraw(ctx, &models.RequestWithdraw{Order: tt.order, Sum: tt.sum}, userService, "user", tt.key)
+			assert.Len(t, store.saved, tt.saved)
+			if tt.code != 0 {
+				require.NotNil(t, cError)
+				assert.Equal(t, tt.code, cError.Code)
+				return
+			}
+			require.Nil(t, cError)
+			assert.Equal(t, tt.replayed, response.Replayed)
+			assert.Equal(t, first.StatusCode, response.StatusCode)
+			assert.JSONEq(t, string(first.Body), string(response.Body))
+		})
+	}
+}
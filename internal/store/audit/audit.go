@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	insertEntrySQL = `
+INSERT INTO public.audit_log (actor_id, action, target, amount, client_ip, request_id, details)
+VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+RETURNING id, create_dt`
+	listEntrySQL = `
+SELECT id, actor_id, action, COALESCE(target, ''), amount, COALESCE(client_ip, ''), COALESCE(request_id, ''), details, create_dt
+FROM public.audit_log WHERE true`
+	deleteExpiredSQL = `DELETE FROM public.audit_log WHERE id IN (SELECT id FROM public.audit_log WHERE create_dt < $1 LIMIT $2)`
+)
+
+type Audit struct {
+	dbpool *pgretry.PgxRetry
+	log    *zap.SugaredLogger
+}
+
+func NewAudit(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *Audit {
+	return &Audit{
+		dbpool: dbpool,
+		log:    log,
+	}
+}
+
+func (a *Audit) Add(ctx context.Context, entry *models.AuditEntry) error {
+	return scanEntry(a.dbpool.QueryRow(ctx, insertEntrySQL, entryArgs(entry)...), entry)
+}
+
+// AddTx пишет запись в транзакции действия, чтобы движение баллов не осталось без записи в журнале.
+func (a *Audit) AddTx(ctx context.Context, tx pgx.Tx, entry *models.AuditEntry) error {
+	return scanEntry(tx.QueryRow(ctx, insertEntrySQL, entryArgs(entry)...), entry)
+}
+
+func entryArgs(entry *models.AuditEntry) []any {
+	return []any{entry.ActorID, entry.Action, entry.Target, entry.Amount, entry.ClientIP, entry.RequestID, entry.Details}
+}
+
+func scanEntry(row pgx.Row, entry *models.AuditEntry) error {
+	err := row.Scan(&entry.ID, &entry.CreateDateTime)
+	if err != nil {
+		return fmt.Errorf("ошибка при записи в журнал аудита: %w", err)
+	}
+
+	return nil
+}
+
+func (a *Audit) GetList(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, error) {
+	query := strings.Builder{}
+	query.WriteString(listEntrySQL)
+	var args []any
+	if filter.ActorID != nil {
+		args = append(args, *filter.ActorID)
+		fmt.Fprintf(&query, " AND actor_id = $%d", len(args))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		fmt.Fprintf(&query, " AND action = $%d", len(args))
+	}
+	if filter.Target != "" {
+		args = append(args, filter.Target)
+		fmt.Fprintf(&query, " AND target = $%d", len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		fmt.Fprintf(&query, " AND create_dt >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		fmt.Fprintf(&query, " AND create_dt < $%d", len(args))
+	}
+	if filter.BeforeID > 0 {
+		args = append(args, filter.BeforeID)
+		fmt.Fprintf(&query, " AND id < $%d", len(args))
+	}
+	args = append(args, filter.Limit)
+	fmt.Fprintf(&query, " ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := a.dbpool.Query(ctx, query.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при извлечении журнала аудита: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.AuditEntry, 0, filter.Limit)
+	for rows.Next() {
+		entry := new(models.AuditEntry)
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ActorID,
+			&entry.Action,
+			&entry.Target,
+			&entry.Amount,
+			&entry.ClientIP,
+			&entry.RequestID,
+			&entry.Details,
+			&entry.CreateDateTime,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании записи аудита: %w", err)
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+func (a *Audit) DeleteExpired(ctx context.Context, before time.Time, limit int) (int64, error) {
+	tag, err := a.dbpool.Exec(ctx, deleteExpiredSQL, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении старых записей аудита: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,75 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	searchKeySQL = `
+SELECT user_id, key, fingerprint, status_code, response, create_dt
+FROM public.idempotency_key
+WHERE user_id = $1 AND key = $2 AND create_dt > NOW() - make_interval(secs => $3)`
+	deleteExpiredKeysSQL = `DELETE FROM public.idempotency_key WHERE user_id = $1 AND create_dt <= NOW() - make_interval(secs => $2)`
+	insertKeySQL         = `
+INSERT INTO public.idempotency_key (user_id, key, fingerprint, status_code, response)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING create_dt`
+)
+
+type Idempotency struct {
+	dbpool *pgretry.PgxRetry
+	log    *zap.SugaredLogger
+}
+
+func NewIdempotency(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *Idempotency {
+	return &Idempotency{
+		dbpool: dbpool,
+		log:    log,
+	}
+}
+
+// GetTx возвращает сохраненный ответ по ключу или nil, если ключа нет или срок его хранения истек.
+func (i *Idempotency) GetTx(ctx context.Context, tx pgx.Tx, userID int, key string, ttl time.Duration) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := tx.QueryRow(ctx, searchKeySQL, userID, key, ttl.Seconds()).Scan(
+		&record.UserID,
+		&record.Key,
+		&record.Fingerprint,
+		&record.StatusCode,
+		&record.Response,
+		&record.CreateDateTime,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске ключа идемпотентности: %w", err)
+	}
+
+	return &record, nil
+}
+
+// SaveTx сохраняет ответ по ключу. Просроченные ключи пользователя удаляются здесь же,
+// поэтому отдельная очистка таблицы не нужна.
+func (i *Idempotency) SaveTx(ctx context.Context, tx pgx.Tx, record *models.IdempotencyKey, ttl time.Duration) error {
+	_, err := tx.Exec(ctx, deleteExpiredKeysSQL, record.UserID, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении просроченных ключей идемпотентности: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, insertKeySQL, record.UserID, record.Key, record.Fingerprint, record.StatusCode, record.Response).Scan(&record.CreateDateTime)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении ключа идемпотентности: %w", err)
+	}
+
+	return nil
+}
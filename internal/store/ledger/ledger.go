@@ -0,0 +1,141 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	changeUserBalanceSQL = `UPDATE public.user SET balance=balance+$1 WHERE id=$2 RETURNING balance`
+	insertEntrySQL       = `INSERT INTO public.ledger (user_id, entry_type, contra_account, amount, balance_after, order_id, withdrawal_id, reason, lot_id, transfer_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, create_dt`
+	insertContraEntrySQL = `
+INSERT INTO public.ledger (entry_type, contra_account, amount, order_id, withdrawal_id, reason, lot_id, transfer_id, contra_of, create_dt)
+SELECT entry_type, contra_account, -amount, order_id, withdrawal_id, reason, lot_id, transfer_id, id, create_dt
+FROM public.ledger WHERE id = $1`
+	listEntrySQL = `
+SELECT l.id, l.user_id, l.entry_type, l.contra_account, l.amount, l.balance_after, l.order_id::text, l.withdrawal_id::text, l.reason, l.transfer_id, c.login, l.create_dt
+FROM public.ledger l
+LEFT JOIN public.transfer t ON t.id = l.transfer_id
+LEFT JOIN public.user c ON c.id = CASE WHEN t.from_user_id = l.user_id THEN t.to_user_id ELSE t.from_user_id END
+WHERE l.user_id = $1 AND ($2::bigint = 0 OR l.id < $2::bigint)
+ORDER BY l.id DESC
+LIMIT $3`
+)
+
+type Ledger struct {
+	dbpool       *pgretry.PgxRetry
+	validityDays int
+	log          *zap.SugaredLogger
+}
+
+func NewLedger(cfg *config.Config, log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *Ledger {
+	return &Ledger{
+		dbpool:       dbpool,
+		validityDays: cfg.Points.ValidityDays,
+		log:          log,
+	}
+}
+
+func (l *Ledger) PostTx(ctx context.Context, tx pgx.Tx, entry *models.LedgerEntry) error {
+	err := tx.QueryRow(ctx, changeUserBalanceSQL, entry.Amount, entry.UserID).Scan(&entry.BalanceAfter)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении баланса пользователя: %w", err)
+	}
+
+	err = tx.QueryRow(
+		ctx,
+		insertEntrySQL,
+		entry.UserID,
+		entry.EntryType,
+		entry.ContraAccount,
+		entry.Amount,
+		entry.BalanceAfter,
+		entry.OrderID,
+		entry.WithdrawalID,
+		entry.Reason,
+		entry.LotID,
+		entry.TransferID,
+	).Scan(&entry.ID, &entry.CreateDateTime)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении проводки: %w", err)
+	}
+
+	// парная строка по контрсчету с противоположной суммой, чтобы проводка сходилась в ноль
+	_, err = tx.Exec(ctx, insertContraEntrySQL, entry.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении проводки по контрсчету: %w", err)
+	}
+
+	// лоты меняются вместе с балансом, чтобы сумма остатков по лотам всегда совпадала с ним
+	switch {
+	case entry.EntryType == models.LedgerExpiry:
+		return nil
+	case entry.EntryType == models.LedgerReversal:
+		return l.restoreLotsTx(ctx, tx, entry)
+	case entry.EntryType == models.LedgerTransfer && entry.Amount > 0:
+		return l.transferLotsTx(ctx, tx, entry)
+	case entry.Amount > 0:
+		return l.addLotTx(ctx, tx, entry, entry.Amount)
+	default:
+		return l.consumeLotsTx(ctx, tx, entry)
+	}
+}
+
+// Adjust проводит ручную корректировку отдельной транзакцией и не допускает ухода баланса в минус.
+// posted, если задан, выполняется в той же транзакции после проводки.
+func (l *Ledger) Adjust(ctx context.Context, entry *models.LedgerEntry, posted func(tx pgx.Tx) error) error {
+	return l.dbpool.RunInTx(ctx, func(tx pgx.Tx) error {
+		if err := l.PostTx(ctx, tx, entry); err != nil {
+			return err
+		}
+		if entry.BalanceAfter < 0 {
+			return models.ErrInsufficientBalance
+		}
+		if posted == nil {
+			return nil
+		}
+
+		return posted(tx)
+	})
+}
+
+func (l *Ledger) GetListByUserID(ctx context.Context, userID int, beforeID int64, limit int) ([]*models.LedgerEntry, error) {
+	rows, err := l.dbpool.Query(ctx, listEntrySQL, userID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при извлечении проводок: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.LedgerEntry
+	for rows.Next() {
+		entry := new(models.LedgerEntry)
+		err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.EntryType,
+			&entry.ContraAccount,
+			&entry.Amount,
+			&entry.BalanceAfter,
+			&entry.OrderID,
+			&entry.WithdrawalID,
+			&entry.Reason,
+			&entry.TransferID,
+			&entry.Counterparty,
+			&entry.CreateDateTime,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании проводки: %w", err)
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,249 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	insertLotSQL = `
+INSERT INTO public.point_lot (user_id, ledger_id, order_id, amount, remaining, expires_at)
+VALUES ($1, $2, $3, $4, $4, CASE WHEN $5::int > 0 THEN NOW() + make_interval(days => $5::int) END)`
+	searchActiveLotsSQL = `
+SELECT id, user_id, order_id::text, remaining, expires_at FROM public.point_lot
+WHERE user_id = $1 AND remaining > 0
+ORDER BY create_dt, id
+FOR UPDATE`
+	searchExpiredLotsSQL = `
+SELECT id, user_id, order_id::text, remaining, expires_at FROM public.point_lot
+WHERE user_id = $1 AND remaining > 0 AND expires_at <= $2
+ORDER BY expires_at, id
+FOR UPDATE`
+	consumeLotSQL  = `UPDATE public.point_lot SET remaining = remaining - $1 WHERE id = $2`
+	insertUsageSQL = `INSERT INTO public.point_lot_usage (ledger_id, lot_id, amount) VALUES ($1, $2, $3)`
+	restoreLotsSQL = `
+UPDATE public.point_lot p SET remaining = p.remaining + u.amount
+FROM public.point_lot_usage u
+JOIN public.ledger l ON l.id = u.ledger_id
+WHERE u.lot_id = p.id AND l.entry_type = 'WITHDRAWAL' AND l.withdrawal_id = $1
+RETURNING u.amount`
+	transferLotsSQL = `
+INSERT INTO public.point_lot (user_id, ledger_id, order_id, amount, remaining, expires_at, create_dt)
+SELECT $1, $2, p.order_id, u.amount, u.amount, p.expires_at, p.create_dt
+FROM public.point_lot_usage u
+JOIN public.point_lot p ON p.id = u.lot_id
+JOIN public.ledger l ON l.id = u.ledger_id
+WHERE l.entry_type = 'TRANSFER' AND l.transfer_id = $3 AND l.amount < 0
+RETURNING amount`
+	lockUserSQL           = `SELECT id FROM public.user WHERE id = $1 FOR UPDATE`
+	searchExpiredUsersSQL = `SELECT DISTINCT user_id FROM public.point_lot WHERE remaining > 0 AND expires_at <= $1 LIMIT $2`
+	searchExpiringSQL     = `
+SELECT SUM(remaining), date_trunc('day', expires_at) AS day FROM public.point_lot
+WHERE user_id = $1 AND remaining > 0 AND expires_at > NOW() AND expires_at <= $2
+GROUP BY day ORDER BY day`
+)
+
+func (l *Ledger) addLotTx(ctx context.Context, tx pgx.Tx, entry *models.LedgerEntry, amount int) error {
+	_, err := tx.Exec(ctx, insertLotSQL, entry.UserID, entry.ID, entry.OrderID, amount, l.validityDays)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании лота баллов: %w", err)
+	}
+
+	return nil
+}
+
+// consumeLotsTx расходует лоты начиная с самых старых (FIFO). Нехватка остатков в лотах означает,
+// что баланс разошелся с лотами: проводка не блокируется, а расхождение логируется для сверки.
+func (l *Ledger) consumeLotsTx(ctx context.Context, tx pgx.Tx, entry *models.LedgerEntry) error {
+	lots, err := l.queryLots(ctx, tx, searchActiveLotsSQL, entry.UserID)
+	if err != nil {
+		return err
+	}
+
+	need := -entry.Amount
+	for _, lot := range lots {
+		if need == 0 {
+			break
+		}
+		take := min(need, lot.Remaining)
+		if _, err := tx.Exec(ctx, consumeLotSQL, take, lot.ID); err != nil {
+			return fmt.Errorf("ошибка при списании из лота баллов: %w", err)
+		}
+		if _, err := tx.Exec(ctx, insertUsageSQL, entry.ID, lot.ID, take); err != nil {
+			return fmt.Errorf("ошибка при сохранении расхода лота: %w", err)
+		}
+		need -= take
+	}
+	if need > 0 {
+		l.log.Warnf("ledger entry %d of user %d exceeds point lots by %d", entry.ID, entry.UserID, need)
+	}
+
+	return nil
+}
+
+// restoreLotsTx возвращает баллы отмененного списания в те лоты, из которых они были потрачены.
+// Если лот уже сгорел, баллы сгорят при следующей проверке. Списания, сделанные до появления лотов,
+// возвращаются новым лотом.
+func (l *Ledger) restoreLotsTx(ctx context.Context, tx pgx.Tx, entry *models.LedgerEntry) error {
+	rows, err := tx.Query(ctx, restoreLotsSQL, entry.WithdrawalID)
+	if err != nil {
+		return fmt.Errorf("ошибка при возврате баллов в лоты: %w", err)
+	}
+	restored := 0
+	for rows.Next() {
+		var amount int
+		if err := rows.Scan(&amount); err != nil {
+			rows.Close()
+			return fmt.Errorf("ошибка при возврате баллов в лоты: %w", err)
+		}
+		restored += amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при возврате баллов в лоты: %w", err)
+	}
+
+	if rest := entry.Amount - restored; rest > 0 {
+		return l.addLotTx(ctx, tx, entry, rest)
+	}
+
+	return nil
+}
+
+// transferLotsTx переносит получателю лоты отправителя с их сроками и датой начисления, иначе перевод
+// продлевал бы срок действия баллов, а полученные баллы расходовались бы последними и чаще сгорали.
+// Проводка отправителя должна быть сделана раньше.
+func (l *Ledger) transferLotsTx(ctx context.Context, tx pgx.Tx, entry *models.LedgerEntry) error {
+	rows, err := tx.Query(ctx, transferLotsSQL, entry.UserID, entry.ID, entry.TransferID)
+	if err != nil {
+		return fmt.Errorf("ошибка при переносе лотов баллов: %w", err)
+	}
+	moved := 0
+	for rows.Next() {
+		var amount int
+		if err := rows.Scan(&amount); err != nil {
+			rows.Close()
+			return fmt.Errorf("ошибка при переносе лотов баллов: %w", err)
+		}
+		moved += amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при переносе лотов баллов: %w", err)
+	}
+
+	if rest := entry.Amount - moved; rest > 0 {
+		return l.addLotTx(ctx, tx, entry, rest)
+	}
+
+	return nil
+}
+
+// ExpireUserLots сжигает просроченные лоты пользователя, по проводке EXPIRY на каждый лот.
+// expired вызывается в той же транзакции, если сгорел хотя бы один лот.
+func (l *Ledger) ExpireUserLots(ctx context.Context, userID int, now time.Time, expired func(tx pgx.Tx, entries []*models.LedgerEntry) error) ([]*models.LedgerEntry, error) {
+	var entries []*models.LedgerEntry
+	err := l.dbpool.RunInTx(ctx, func(tx pgx.Tx) error {
+		entries = nil
+		// пользователь блокируется первым, как и при любой проводке, иначе возможна взаимная блокировка со списанием
+		var id int
+		if err := tx.QueryRow(ctx, lockUserSQL, userID).Scan(&id); err != nil {
+			return fmt.Errorf("ошибка при блокировке пользователя: %w", err)
+		}
+
+		lots, err := l.queryLots(ctx, tx, searchExpiredLotsSQL, userID, now)
+		if err != nil {
+			return err
+		}
+		for _, lot := range lots {
+			if _, err := tx.Exec(ctx, consumeLotSQL, lot.Remaining, lot.ID); err != nil {
+				return fmt.Errorf("ошибка при сжигании лота баллов: %w", err)
+			}
+			entry := &models.LedgerEntry{
+				UserID:        userID,
+				EntryType:     models.LedgerExpiry,
+				ContraAccount: models.ContraExpiry,
+				Amount:        -lot.Remaining,
+				OrderID:       lot.OrderID,
+				LotID:         &lot.ID,
+			}
+			if err := l.PostTx(ctx, tx, entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		if len(entries) == 0 || expired == nil {
+			return nil
+		}
+
+		return expired(tx, entries)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (l *Ledger) GetUsersWithExpiredLots(ctx context.Context, now time.Time, limit int) ([]int, error) {
+	rows, err := l.dbpool.Query(ctx, searchExpiredUsersSQL, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске просроченных лотов: %w", err)
+	}
+	defer rows.Close()
+
+	var result []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании пользователя: %w", err)
+		}
+		result = append(result, userID)
+	}
+
+	return result, nil
+}
+
+// GetExpiring возвращает суммы, которые сгорят до before, по дням.
+func (l *Ledger) GetExpiring(ctx context.Context, userID int, before time.Time) ([]*models.ExpiringPoints, error) {
+	rows, err := l.dbpool.Query(ctx, searchExpiringSQL, userID, before)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске сгорающих баллов: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.ExpiringPoints
+	for rows.Next() {
+		points := new(models.ExpiringPoints)
+		if err := rows.Scan(&points.Amount, &points.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании сгорающих баллов: %w", err)
+		}
+		result = append(result, points)
+	}
+
+	return result, nil
+}
+
+func (l *Ledger) queryLots(ctx context.Context, tx pgx.Tx, sql string, args ...any) ([]*models.PointLot, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при извлечении лотов баллов: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.PointLot
+	for rows.Next() {
+		lot := new(models.PointLot)
+		if err := rows.Scan(&lot.ID, &lot.UserID, &lot.OrderID, &lot.Remaining, &lot.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании лота баллов: %w", err)
+		}
+		result = append(result, lot)
+	}
+
+	return result, rows.Err()
+}
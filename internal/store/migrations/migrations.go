@@ -0,0 +1,120 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var sqlFS embed.FS
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+type (
+	Migration struct {
+		Version  int
+		Name     string
+		Up       string
+		Down     string
+		Checksum string
+	}
+	Status struct {
+		Version   int
+		Name      string
+		AppliedAt *time.Time
+		Mismatch  bool
+	}
+)
+
+// loadMigrations читает пары NNNN_name.up.sql / NNNN_name.down.sql и возвращает их по возрастанию версии.
+func loadMigrations(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога миграций: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var isUp bool
+		var base string
+		switch {
+		case strings.HasSuffix(fileName, upSuffix):
+			isUp = true
+			base = strings.TrimSuffix(fileName, upSuffix)
+		case strings.HasSuffix(fileName, downSuffix):
+			base = strings.TrimSuffix(fileName, downSuffix)
+		default:
+			continue
+		}
+
+		version, name, err := parseName(base)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное имя файла миграции %s: %w", fileName, err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения миграции %s: %w", fileName, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("миграция %04d имеет разные имена: %s и %s", version, m.Name, name)
+		}
+		if isUp {
+			m.Up = string(body)
+			m.Checksum = checksum(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	result := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("у миграции %04d_%s отсутствует up-скрипт", m.Version, m.Name)
+		}
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+func parseName(base string) (int, string, error) {
+	versionStr, name, found := strings.Cut(base, "_")
+	if !found || name == "" {
+		return 0, "", fmt.Errorf("ожидается формат NNNN_name")
+	}
+
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version <= 0 {
+		return 0, "", fmt.Errorf("некорректная версия %q", versionStr)
+	}
+
+	return version, name, nil
+}
+
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:])
+}
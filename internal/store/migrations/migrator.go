@@ -0,0 +1,255 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+)
+
+const (
+	advisoryLockID         = 7_326_431_918
+	acquireLockSQL         = `SELECT pg_advisory_lock($1)`
+	releaseLockSQL         = `SELECT pg_advisory_unlock($1)`
+	listAppliedSQL         = `SELECT version, name, checksum, applied_at FROM public.schema_version ORDER BY version`
+	insertVersionSQL       = `INSERT INTO public.schema_version (version, name, checksum) VALUES ($1, $2, $3)`
+	deleteVersionSQL       = `DELETE FROM public.schema_version WHERE version=$1`
+	createSchemaVersionSQL = `
+CREATE TABLE IF NOT EXISTS public.schema_version (
+	version integer NOT NULL,
+	name varchar(255) NOT NULL,
+	checksum varchar(64) NOT NULL,
+	applied_at timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT schema_version_pk PRIMARY KEY (version)
+);
+`
+)
+
+type (
+	Migrator struct {
+		dbpool     *pgretry.PgxRetry
+		log        *zap.SugaredLogger
+		migrations []*Migration
+	}
+	applied struct {
+		name      string
+		checksum  string
+		appliedAt time.Time
+	}
+)
+
+func NewMigrator(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) (*Migrator, error) {
+	list, err := loadMigrations(sqlFS, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		dbpool:     dbpool,
+		log:        log,
+		migrations: list,
+	}, nil
+}
+
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(done); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, exists := done[migration.Version]; exists {
+				continue
+			}
+
+			m.log.Infof("applying migration %04d_%s", migration.Version, migration.Name)
+			err := m.apply(ctx, conn, migration.Up, func(exec execFunc) error {
+				return exec(insertVersionSQL, migration.Version, migration.Name, migration.Checksum)
+			})
+			if err != nil {
+				return fmt.Errorf("ошибка применения миграции %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(done); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, exists := done[migration.Version]; !exists {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("у миграции %04d_%s отсутствует down-скрипт", migration.Version, migration.Name)
+			}
+
+			m.log.Infof("reverting migration %04d_%s", migration.Version, migration.Name)
+			err := m.apply(ctx, conn, migration.Down, func(exec execFunc) error {
+				return exec(deleteVersionSQL, migration.Version)
+			})
+			if err != nil {
+				return fmt.Errorf("ошибка отката миграции %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			steps--
+		}
+
+		return nil
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	var result []*Status
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := &Status{Version: migration.Version, Name: migration.Name}
+			if a, exists := done[migration.Version]; exists {
+				appliedAt := a.appliedAt
+				status.AppliedAt = &appliedAt
+				status.Mismatch = a.checksum != migration.Checksum
+			}
+			result = append(result, status)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// Pending возвращает неприменённые миграции. Advisory lock не берется, чтобы проверка готовности
+// не ждала завершения миграции, которую выполняет другая реплика.
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	done, err := m.getApplied(ctx, m.dbpool)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(done); err != nil {
+		return nil, err
+	}
+
+	var result []*Migration
+	for _, migration := range m.migrations {
+		if _, exists := done[migration.Version]; !exists {
+			result = append(result, migration)
+		}
+	}
+
+	return result, nil
+}
+
+type (
+	execFunc func(sql string, args ...any) error
+	querier  interface {
+		Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	}
+)
+
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, script string, record func(exec execFunc) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	err = record(func(sql string, args ...any) error {
+		_, err := tx.Exec(ctx, sql, args...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка записи версии схемы: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.dbpool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, acquireLockSQL, advisoryLockID); err != nil {
+		return fmt.Errorf("ошибка получения advisory lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), releaseLockSQL, advisoryLockID); err != nil {
+			m.log.Errorf("failed to release migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createSchemaVersionSQL); err != nil {
+		return fmt.Errorf("ошибка создания таблицы schema_version: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) getApplied(ctx context.Context, conn querier) (map[int]applied, error) {
+	rows, err := conn.Query(ctx, listAppliedSQL)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при извлечении версий схемы: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int]applied)
+	for rows.Next() {
+		var version int
+		var a applied
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании версии схемы: %w", err)
+		}
+		result[version] = a
+	}
+
+	return result, rows.Err()
+}
+
+func (m *Migrator) verify(done map[int]applied) error {
+	known := make(map[int]*Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, a := range done {
+		migration, exists := known[version]
+		if !exists {
+			return fmt.Errorf("в базе применена неизвестная миграция %04d_%s", version, a.name)
+		}
+		if migration.Checksum != a.checksum {
+			return fmt.Errorf("контрольная сумма миграции %04d_%s не совпадает с применённой", version, migration.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS public."withdrawal";
+DROP TABLE IF EXISTS public."order";
+DROP TYPE IF EXISTS statuses;
+DROP TABLE IF EXISTS public."user";
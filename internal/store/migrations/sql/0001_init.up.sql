@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS public."user" (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	login varchar(255) not null,
+	password VARCHAR(255) not null,
+	balance bigint NOT NULL DEFAULT 0,
+	CONSTRAINT user_login UNIQUE ("login"),
+	CONSTRAINT user_pk PRIMARY KEY ("id")
+);
+
+DO $$
+BEGIN
+IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'statuses') THEN
+	CREATE TYPE statuses AS ENUM ('NEW', 'PROCESSING', 'INVALID', 'PROCESSED');
+END IF;
+END$$;
+
+CREATE TABLE IF NOT EXISTS public."order" (
+	id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	accrual bigint DEFAULT NUll,
+	status statuses DEFAULT 'NEW' NOT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	block bool DEFAULT false NOT NULL,
+	CONSTRAINT order_pk PRIMARY KEY (id),
+	CONSTRAINT order_id_idx UNIQUE (user_id,id)
+);
+
+CREATE TABLE IF NOT EXISTS public."withdrawal" (
+	id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	withdrawal bigint DEFAULT NUll,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT withdrawal_pk PRIMARY KEY (id),
+	CONSTRAINT withdrawal_id_idx UNIQUE (user_id,id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.outbox;
+DROP TYPE IF EXISTS outbox_statuses;
@@ -0,0 +1,17 @@
+CREATE TYPE outbox_statuses AS ENUM ('PENDING', 'PUBLISHED', 'DEAD');
+
+CREATE TABLE public.outbox (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	event_type varchar(64) NOT NULL,
+	aggregate_id varchar(64) NOT NULL,
+	payload jsonb NOT NULL,
+	status outbox_statuses DEFAULT 'PENDING' NOT NULL,
+	attempts integer DEFAULT 0 NOT NULL,
+	last_error text DEFAULT NULL,
+	next_attempt_dt timestamptz DEFAULT NOW() NOT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	publish_dt timestamptz DEFAULT NULL,
+	CONSTRAINT outbox_pk PRIMARY KEY (id)
+);
+
+CREATE INDEX outbox_pending_idx ON public.outbox (next_attempt_dt, id) WHERE status = 'PENDING';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.ledger;
+DROP FUNCTION IF EXISTS ledger_immutable();
+DROP TYPE IF EXISTS ledger_entry_types;
@@ -0,0 +1,49 @@
+-- Each row is one movement between the user's account and a contra account
+-- (accrual, withdrawal or adjustment); the contra account implicitly receives
+-- the opposite amount, so every entry balances to zero.
+CREATE TYPE ledger_entry_types AS ENUM ('ACCRUAL', 'WITHDRAWAL', 'ADJUSTMENT');
+
+CREATE TABLE public.ledger (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	user_id bigint NOT NULL,
+	entry_type ledger_entry_types NOT NULL,
+	contra_account varchar(32) NOT NULL,
+	amount bigint NOT NULL,
+	balance_after bigint NOT NULL,
+	order_id bigint DEFAULT NULL,
+	withdrawal_id bigint DEFAULT NULL,
+	reason text DEFAULT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT ledger_pk PRIMARY KEY (id),
+	CONSTRAINT ledger_amount_check CHECK (amount <> 0),
+	CONSTRAINT ledger_source_check CHECK (
+		(entry_type = 'ACCRUAL' AND order_id IS NOT NULL) OR
+		(entry_type = 'WITHDRAWAL' AND withdrawal_id IS NOT NULL) OR
+		(entry_type = 'ADJUSTMENT' AND reason IS NOT NULL)
+	)
+);
+
+CREATE INDEX ledger_user_idx ON public.ledger (user_id, id);
+
+CREATE FUNCTION ledger_immutable() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'ledger entries are immutable';
+END$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_immutable_trg BEFORE UPDATE OR DELETE ON public.ledger
+	FOR EACH ROW EXECUTE FUNCTION ledger_immutable();
+
+INSERT INTO public.ledger (user_id, entry_type, contra_account, amount, balance_after, order_id, withdrawal_id, create_dt)
+SELECT user_id, entry_type, contra_account, amount,
+	SUM(amount) OVER (PARTITION BY user_id ORDER BY create_dt, entry_type, source_id),
+	order_id, withdrawal_id, create_dt
+FROM (
+	SELECT user_id, 'ACCRUAL'::ledger_entry_types AS entry_type, 'accrual' AS contra_account, accrual AS amount,
+		id AS order_id, NULL::bigint AS withdrawal_id, id AS source_id, create_dt
+	FROM public."order" WHERE status = 'PROCESSED' AND accrual > 0
+	UNION ALL
+	SELECT user_id, 'WITHDRAWAL'::ledger_entry_types, 'withdrawal', -withdrawal,
+		NULL::bigint, id, id, create_dt
+	FROM public.withdrawal WHERE withdrawal > 0
+) AS movements
+ORDER BY create_dt, entry_type, source_id;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.reconciliation_discrepancy;
+DROP TABLE IF EXISTS public.reconciliation_run;
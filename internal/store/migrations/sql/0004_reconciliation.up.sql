@@ -0,0 +1,24 @@
+CREATE TABLE public.reconciliation_run (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	auto_correct bool DEFAULT false NOT NULL,
+	users_checked integer DEFAULT 0 NOT NULL,
+	discrepancies integer DEFAULT 0 NOT NULL,
+	corrected integer DEFAULT 0 NOT NULL,
+	start_dt timestamptz DEFAULT NOW() NOT NULL,
+	finish_dt timestamptz DEFAULT NULL,
+	CONSTRAINT reconciliation_run_pk PRIMARY KEY (id)
+);
+
+CREATE TABLE public.reconciliation_discrepancy (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	run_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	balance bigint NOT NULL,
+	expected bigint NOT NULL,
+	corrected bool DEFAULT false NOT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT reconciliation_discrepancy_pk PRIMARY KEY (id),
+	CONSTRAINT reconciliation_discrepancy_run_fk FOREIGN KEY (run_id) REFERENCES public.reconciliation_run (id) ON DELETE CASCADE
+);
+
+CREATE INDEX reconciliation_discrepancy_run_idx ON public.reconciliation_discrepancy (run_id);
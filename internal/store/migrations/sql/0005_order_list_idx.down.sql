@@ -0,0 +1 @@
+DROP INDEX IF EXISTS order_user_create_dt_idx;
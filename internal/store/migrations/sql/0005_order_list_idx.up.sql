@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS order_user_create_dt_idx ON public."order" (user_id, create_dt DESC, id DESC);
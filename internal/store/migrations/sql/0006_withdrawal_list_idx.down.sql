@@ -0,0 +1 @@
+DROP INDEX IF EXISTS withdrawal_user_create_dt_idx;
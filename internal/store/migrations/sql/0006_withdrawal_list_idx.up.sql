@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS withdrawal_user_create_dt_idx ON public."withdrawal" (user_id, create_dt DESC, id DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.refresh_token;
+DROP TABLE IF EXISTS public.session;
@@ -0,0 +1,22 @@
+CREATE TABLE public.session (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	user_id bigint NOT NULL,
+	expires_dt timestamptz NOT NULL,
+	revoke_dt timestamptz DEFAULT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT session_pk PRIMARY KEY (id)
+);
+
+CREATE INDEX session_user_idx ON public.session (user_id);
+
+CREATE TABLE public.refresh_token (
+	token_hash varchar(64) NOT NULL,
+	session_id bigint NOT NULL,
+	expires_dt timestamptz NOT NULL,
+	use_dt timestamptz DEFAULT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT refresh_token_pk PRIMARY KEY (token_hash),
+	CONSTRAINT refresh_token_session_fk FOREIGN KEY (session_id) REFERENCES public.session (id) ON DELETE CASCADE
+);
+
+CREATE INDEX refresh_token_session_idx ON public.refresh_token (session_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.rate_limit_lock;
+DROP TABLE IF EXISTS public.rate_limit_event;
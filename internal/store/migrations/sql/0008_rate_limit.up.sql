@@ -0,0 +1,12 @@
+CREATE TABLE public.rate_limit_event (
+	key text NOT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX rate_limit_event_key_idx ON public.rate_limit_event (key, create_dt);
+
+CREATE TABLE public.rate_limit_lock (
+	key text NOT NULL,
+	locked_until timestamptz NOT NULL,
+	CONSTRAINT rate_limit_lock_pk PRIMARY KEY (key)
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS order_next_attempt_idx;
+
+ALTER TABLE public."order"
+	DROP COLUMN IF EXISTS stalled_at,
+	DROP COLUMN IF EXISTS next_attempt_at,
+	DROP COLUMN IF EXISTS last_polled_at,
+	DROP COLUMN IF EXISTS attempt_count;
@@ -0,0 +1,10 @@
+ALTER TABLE public."order"
+	ADD COLUMN attempt_count int DEFAULT 0 NOT NULL,
+	ADD COLUMN last_polled_at timestamptz DEFAULT NULL,
+	ADD COLUMN next_attempt_at timestamptz DEFAULT NOW() NOT NULL,
+	ADD COLUMN stalled_at timestamptz DEFAULT NULL;
+
+UPDATE public."order" SET next_attempt_at = create_dt;
+
+CREATE INDEX order_next_attempt_idx ON public."order" (next_attempt_at)
+	WHERE status IN ('NEW', 'PROCESSING') AND stalled_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE public."order"
+	ADD COLUMN block bool DEFAULT false NOT NULL,
+	DROP COLUMN IF EXISTS lease_expires_at,
+	DROP COLUMN IF EXISTS lease_owner;
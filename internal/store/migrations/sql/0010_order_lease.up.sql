@@ -0,0 +1,4 @@
+ALTER TABLE public."order"
+	ADD COLUMN lease_owner text DEFAULT NULL,
+	ADD COLUMN lease_expires_at timestamptz DEFAULT NULL,
+	DROP COLUMN block;
@@ -0,0 +1,2 @@
+ALTER TABLE public."order"
+	DROP COLUMN IF EXISTS trace_parent;
@@ -0,0 +1,2 @@
+ALTER TABLE public."order"
+	ADD COLUMN trace_parent varchar(55) DEFAULT NULL;
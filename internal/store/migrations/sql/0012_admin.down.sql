@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS public.audit_log;
+
+ALTER TABLE public."user"
+	DROP COLUMN IF EXISTS blocked_reason,
+	DROP COLUMN IF EXISTS blocked_at,
+	DROP COLUMN IF EXISTS role;
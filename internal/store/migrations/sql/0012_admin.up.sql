@@ -0,0 +1,16 @@
+ALTER TABLE public."user"
+	ADD COLUMN role varchar(16) DEFAULT 'user' NOT NULL,
+	ADD COLUMN blocked_at timestamptz DEFAULT NULL,
+	ADD COLUMN blocked_reason text DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS public.audit_log (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	actor_id bigint DEFAULT NULL,
+	action varchar(64) NOT NULL,
+	target varchar(255) DEFAULT NULL,
+	details jsonb DEFAULT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT audit_log_pk PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_create_dt_idx ON public.audit_log (create_dt);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS audit_log_action_idx;
+DROP INDEX IF EXISTS audit_log_actor_idx;
+
+ALTER TABLE public.audit_log
+	DROP COLUMN IF EXISTS request_id,
+	DROP COLUMN IF EXISTS client_ip,
+	DROP COLUMN IF EXISTS amount;
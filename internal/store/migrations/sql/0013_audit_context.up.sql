@@ -0,0 +1,7 @@
+ALTER TABLE public.audit_log
+	ADD COLUMN amount bigint DEFAULT NULL,
+	ADD COLUMN client_ip varchar(45) DEFAULT NULL,
+	ADD COLUMN request_id varchar(64) DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON public.audit_log (actor_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON public.audit_log (action, id);
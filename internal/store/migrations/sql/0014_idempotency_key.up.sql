@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS public.idempotency_key (
+	user_id bigint NOT NULL,
+	key varchar(255) NOT NULL,
+	fingerprint char(64) NOT NULL,
+	status_code smallint NOT NULL,
+	response jsonb NOT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT idempotency_key_pk PRIMARY KEY (user_id, key),
+	CONSTRAINT idempotency_key_user_fk FOREIGN KEY (user_id) REFERENCES public."user" (id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Новое значение перечисления нельзя использовать в той же транзакции, где оно добавлено,
+-- поэтому ограничение ledger_source_check обновляется отдельной миграцией.
+ALTER TYPE ledger_entry_types ADD VALUE IF NOT EXISTS 'REVERSAL';
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS ledger_reversal_idx;
+
+ALTER TABLE public.ledger DROP CONSTRAINT ledger_source_check;
+ALTER TABLE public.ledger ADD CONSTRAINT ledger_source_check CHECK (
+	(entry_type = 'ACCRUAL' AND order_id IS NOT NULL) OR
+	(entry_type = 'WITHDRAWAL' AND withdrawal_id IS NOT NULL) OR
+	(entry_type = 'ADJUSTMENT' AND reason IS NOT NULL)
+) NOT VALID;
+
+ALTER TABLE public."withdrawal"
+	DROP CONSTRAINT IF EXISTS withdrawal_status_check,
+	DROP COLUMN IF EXISTS reversal_reason,
+	DROP COLUMN IF EXISTS status_dt,
+	DROP COLUMN IF EXISTS status;
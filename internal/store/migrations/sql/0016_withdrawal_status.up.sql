@@ -0,0 +1,18 @@
+-- Уже созданные списания считаются завершенными, новые создаются в статусе PENDING
+-- и переходят в COMPLETED или REVERSED по обратному вызову партнера или действию администратора.
+ALTER TABLE public."withdrawal"
+	ADD COLUMN status varchar(16) DEFAULT 'COMPLETED' NOT NULL,
+	ADD COLUMN status_dt timestamptz DEFAULT NULL,
+	ADD COLUMN reversal_reason text DEFAULT NULL,
+	ADD CONSTRAINT withdrawal_status_check CHECK (status IN ('PENDING', 'COMPLETED', 'REVERSED'));
+
+ALTER TABLE public."withdrawal" ALTER COLUMN status SET DEFAULT 'PENDING';
+
+ALTER TABLE public.ledger DROP CONSTRAINT ledger_source_check;
+ALTER TABLE public.ledger ADD CONSTRAINT ledger_source_check CHECK (
+	(entry_type = 'ACCRUAL' AND order_id IS NOT NULL) OR
+	(entry_type IN ('WITHDRAWAL', 'REVERSAL') AND withdrawal_id IS NOT NULL) OR
+	(entry_type = 'ADJUSTMENT' AND reason IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_reversal_idx ON public.ledger (withdrawal_id) WHERE entry_type = 'REVERSAL';
@@ -0,0 +1,2 @@
+-- PostgreSQL не умеет удалять значения перечисления, а проводки неизменяемы,
+-- поэтому значение EXPIRY остается в типе.
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS public.point_lot_usage;
+DROP TABLE IF EXISTS public.point_lot;
+
+ALTER TABLE public.ledger DROP CONSTRAINT ledger_source_check;
+ALTER TABLE public.ledger ADD CONSTRAINT ledger_source_check CHECK (
+	(entry_type = 'ACCRUAL' AND order_id IS NOT NULL) OR
+	(entry_type IN ('WITHDRAWAL', 'REVERSAL') AND withdrawal_id IS NOT NULL) OR
+	(entry_type = 'ADJUSTMENT' AND reason IS NOT NULL)
+) NOT VALID;
+
+ALTER TABLE public.ledger DROP COLUMN IF EXISTS lot_id;
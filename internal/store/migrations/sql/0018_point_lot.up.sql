@@ -0,0 +1,40 @@
+-- Каждое поступление баллов образует лот со своим сроком действия, списания расходуют лоты
+-- начиная с ближайших к сгоранию, а расход фиксируется в point_lot_usage, чтобы отмена списания
+-- вернула баллы в те же лоты. Сумма remaining по лотам пользователя равна его балансу.
+ALTER TABLE public.ledger ADD COLUMN lot_id bigint DEFAULT NULL;
+
+ALTER TABLE public.ledger DROP CONSTRAINT ledger_source_check;
+ALTER TABLE public.ledger ADD CONSTRAINT ledger_source_check CHECK (
+	(entry_type = 'ACCRUAL' AND order_id IS NOT NULL) OR
+	(entry_type IN ('WITHDRAWAL', 'REVERSAL') AND withdrawal_id IS NOT NULL) OR
+	(entry_type = 'ADJUSTMENT' AND reason IS NOT NULL) OR
+	(entry_type = 'EXPIRY' AND lot_id IS NOT NULL)
+);
+
+CREATE TABLE IF NOT EXISTS public.point_lot (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	user_id bigint NOT NULL,
+	ledger_id bigint DEFAULT NULL,
+	order_id bigint DEFAULT NULL,
+	amount bigint NOT NULL,
+	remaining bigint NOT NULL,
+	expires_at timestamptz DEFAULT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT point_lot_pk PRIMARY KEY (id),
+	CONSTRAINT point_lot_remaining_check CHECK (remaining >= 0 AND remaining <= amount)
+);
+
+CREATE INDEX IF NOT EXISTS point_lot_user_idx ON public.point_lot (user_id, expires_at) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS point_lot_expires_idx ON public.point_lot (expires_at) WHERE remaining > 0;
+
+CREATE TABLE IF NOT EXISTS public.point_lot_usage (
+	ledger_id bigint NOT NULL,
+	lot_id bigint NOT NULL,
+	amount bigint NOT NULL,
+	CONSTRAINT point_lot_usage_pk PRIMARY KEY (ledger_id, lot_id),
+	CONSTRAINT point_lot_usage_lot_fk FOREIGN KEY (lot_id) REFERENCES public.point_lot (id)
+);
+
+-- баллы, начисленные до введения сроков, не сгорают
+INSERT INTO public.point_lot (user_id, amount, remaining)
+SELECT id, balance, balance FROM public."user" WHERE balance > 0;
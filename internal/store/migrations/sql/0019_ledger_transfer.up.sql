@@ -0,0 +1,2 @@
+-- Значение добавляется отдельной миграцией по той же причине, что и REVERSAL в 0015.
+ALTER TYPE ledger_entry_types ADD VALUE IF NOT EXISTS 'TRANSFER';
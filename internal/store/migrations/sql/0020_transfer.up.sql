@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS public.transfer (
+	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
+	from_user_id bigint NOT NULL,
+	to_user_id bigint NOT NULL,
+	amount bigint NOT NULL,
+	create_dt timestamptz DEFAULT NOW() NOT NULL,
+	CONSTRAINT transfer_pk PRIMARY KEY (id),
+	CONSTRAINT transfer_amount_check CHECK (amount > 0),
+	CONSTRAINT transfer_users_check CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS transfer_from_user_idx ON public.transfer (from_user_id, create_dt);
+
+ALTER TABLE public.ledger ADD COLUMN transfer_id bigint DEFAULT NULL;
+
+ALTER TABLE public.ledger DROP CONSTRAINT ledger_source_check;
+ALTER TABLE public.ledger ADD CONSTRAINT ledger_source_check CHECK (
+	(entry_type = 'ACCRUAL' AND order_id IS NOT NULL) OR
+	(entry_type IN ('WITHDRAWAL', 'REVERSAL') AND withdrawal_id IS NOT NULL) OR
+	(entry_type = 'ADJUSTMENT' AND reason IS NOT NULL) OR
+	(entry_type = 'EXPIRY' AND lot_id IS NOT NULL) OR
+	(entry_type = 'TRANSFER' AND transfer_id IS NOT NULL)
+);
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS ledger_accrual_idx;
+DROP INDEX IF EXISTS ledger_bonus_idx;
+
+ALTER TABLE public.ledger DROP CONSTRAINT ledger_source_check;
+ALTER TABLE public.ledger ADD CONSTRAINT ledger_source_check CHECK (
+	(entry_type = 'ACCRUAL' AND order_id IS NOT NULL) OR
+	(entry_type IN ('WITHDRAWAL', 'REVERSAL') AND withdrawal_id IS NOT NULL) OR
+	(entry_type = 'ADJUSTMENT' AND reason IS NOT NULL) OR
+	(entry_type = 'EXPIRY' AND lot_id IS NOT NULL) OR
+	(entry_type = 'TRANSFER' AND transfer_id IS NOT NULL)
+) NOT VALID;
+
+ALTER TABLE public.user DROP COLUMN IF EXISTS tier_dt;
+ALTER TABLE public.user DROP COLUMN IF EXISTS tier;
@@ -0,0 +1,14 @@
+ALTER TABLE public.user ADD COLUMN tier varchar(32) DEFAULT NULL;
+ALTER TABLE public.user ADD COLUMN tier_dt timestamptz DEFAULT NULL;
+
+ALTER TABLE public.ledger DROP CONSTRAINT ledger_source_check;
+ALTER TABLE public.ledger ADD CONSTRAINT ledger_source_check CHECK (
+	(entry_type IN ('ACCRUAL', 'BONUS') AND order_id IS NOT NULL) OR
+	(entry_type IN ('WITHDRAWAL', 'REVERSAL') AND withdrawal_id IS NOT NULL) OR
+	(entry_type = 'ADJUSTMENT' AND reason IS NOT NULL) OR
+	(entry_type = 'EXPIRY' AND lot_id IS NOT NULL) OR
+	(entry_type = 'TRANSFER' AND transfer_id IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_bonus_idx ON public.ledger (order_id) WHERE entry_type = 'BONUS';
+CREATE INDEX IF NOT EXISTS ledger_accrual_idx ON public.ledger (user_id, create_dt) WHERE entry_type = 'ACCRUAL';
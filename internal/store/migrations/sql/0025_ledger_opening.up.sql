@@ -0,0 +1,17 @@
+-- Перенесенная в 0003 история могла не сходиться с сохраненным балансом. Разница проводится
+-- корректировкой по контрсчету opening: журнал сходится с балансом, а сверка этот контрсчет
+-- не учитывает и продолжает показывать расхождение, которое было до появления журнала.
+INSERT INTO public.ledger (user_id, entry_type, contra_account, amount, balance_after, reason)
+SELECT u.id, 'ADJUSTMENT', 'opening', u.balance - COALESCE(l.total, 0), u.balance, 'opening balance'
+FROM public."user" u
+LEFT JOIN (
+	SELECT user_id, SUM(amount) AS total FROM public.ledger WHERE user_id IS NOT NULL GROUP BY user_id
+) l ON l.user_id = u.id
+WHERE u.balance <> COALESCE(l.total, 0)
+ORDER BY u.id;
+
+INSERT INTO public.ledger (entry_type, contra_account, amount, reason, contra_of, create_dt)
+SELECT entry_type, contra_account, -amount, reason, id, create_dt
+FROM public.ledger
+WHERE contra_account = 'opening' AND user_id IS NOT NULL
+ORDER BY id;
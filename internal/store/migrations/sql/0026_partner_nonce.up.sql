@@ -0,0 +1,9 @@
+-- Использованные подписи обратных вызовов партнера хранятся до конца окна допустимой метки времени,
+-- чтобы перехваченный вызов нельзя было повторить в этом окне.
+CREATE TABLE IF NOT EXISTS public.partner_nonce (
+	signature char(64) NOT NULL,
+	expires_at timestamptz NOT NULL,
+	CONSTRAINT partner_nonce_pk PRIMARY KEY (signature)
+);
+
+CREATE INDEX IF NOT EXISTS partner_nonce_expires_idx ON public.partner_nonce (expires_at);
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"time"
 )
 
@@ -11,6 +12,8 @@ type (
 		Login        string `json:"login"`
 		PasswordHash string `json:"password"`
 		Balance      int
+		Role         string
+		BlockedAt    *time.Time
 	}
 	Order struct {
 		ID             string      `json:"number"`
@@ -18,14 +21,150 @@ type (
 		Status         OrderStatus `json:"status"`
 		Accrual        *int        `json:"accrual,omitempty"`
 		CreateDateTime time.Time   `json:"uploaded_at"`
+		AttemptCount   int         `json:"-"`
+		TraceParent    string      `json:"-"`
 	}
 	Withdrawal struct {
-		ID             string    `json:"order"`
-		UserID         int       `json:"-"`
-		Withdrawal     int       `json:"sum"`
-		CreateDateTime time.Time `json:"processed_at"`
+		ID             string           `json:"order"`
+		UserID         int              `json:"-"`
+		Withdrawal     int              `json:"sum"`
+		Status         WithdrawalStatus `json:"status"`
+		StatusDateTime *time.Time       `json:"status_changed_at,omitempty"`
+		ReversalReason *string          `json:"reversal_reason,omitempty"`
+		CreateDateTime time.Time        `json:"processed_at"`
 	}
-	OrderStatus string
+	OutboxEvent struct {
+		ID             int64           `json:"id"`
+		EventType      string          `json:"event_type"`
+		AggregateID    string          `json:"aggregate_id"`
+		Payload        json.RawMessage `json:"payload"`
+		Status         OutboxStatus    `json:"-"`
+		Attempts       int             `json:"-"`
+		CreateDateTime time.Time       `json:"created_at"`
+	}
+	OrderStatusPayload struct {
+		Order   string      `json:"order"`
+		UserID  int         `json:"user_id"`
+		Status  OrderStatus `json:"status"`
+		Accrual float64     `json:"accrual,omitempty"`
+	}
+	LedgerEntry struct {
+		ID             int64           `json:"id"`
+		UserID         int             `json:"-"`
+		EntryType      LedgerEntryType `json:"type"`
+		ContraAccount  string          `json:"-"`
+		Amount         int             `json:"amount"`
+		BalanceAfter   int             `json:"balance"`
+		OrderID        *string         `json:"order,omitempty"`
+		WithdrawalID   *string         `json:"withdrawal,omitempty"`
+		Reason         *string         `json:"reason,omitempty"`
+		LotID          *int64          `json:"-"`
+		TransferID     *int64          `json:"transfer,omitempty"`
+		Counterparty   *string         `json:"counterparty,omitempty"`
+		CreateDateTime time.Time       `json:"created_at"`
+	}
+	Transfer struct {
+		ID             int64     `json:"id"`
+		FromUserID     int       `json:"-"`
+		ToUserID       int       `json:"-"`
+		ToLogin        string    `json:"to"`
+		Amount         int       `json:"-"`
+		CreateDateTime time.Time `json:"created_at"`
+	}
+	PointLot struct {
+		ID        int64
+		UserID    int
+		OrderID   *string
+		Remaining int
+		ExpiresAt *time.Time
+	}
+	ExpiringPoints struct {
+		Amount    int
+		ExpiresAt time.Time
+	}
+	// UserTier - сохраненный уровень пользователя (пустая строка - не присваивался)
+	// и сумма начислений по заказам за расчетный период
+	UserTier struct {
+		UserID  int
+		Tier    string
+		Accrued int
+	}
+	ReconciliationRun struct {
+		ID             int64          `json:"id"`
+		AutoCorrect    bool           `json:"auto_correct"`
+		UsersChecked   int            `json:"users_checked"`
+		Discrepancies  []*Discrepancy `json:"discrepancies"`
+		Corrected      int            `json:"corrected"`
+		StartDateTime  time.Time      `json:"started_at"`
+		FinishDateTime *time.Time     `json:"finished_at,omitempty"`
+	}
+	Discrepancy struct {
+		ID             int64     `json:"id"`
+		RunID          int64     `json:"-"`
+		UserID         int       `json:"user_id"`
+		Login          string    `json:"login"`
+		Balance        int       `json:"balance"`
+		Expected       int       `json:"expected"`
+		Corrected      bool      `json:"corrected"`
+		CreateDateTime time.Time `json:"created_at"`
+	}
+	Session struct {
+		ID        int64
+		UserID    int
+		Login     string
+		Role      string
+		ExpiresAt time.Time
+		RevokedAt *time.Time
+	}
+	AuditEntry struct {
+		ID             int64           `json:"id"`
+		ActorID        *int            `json:"actor_id,omitempty"`
+		Action         string          `json:"action"`
+		Target         string          `json:"target,omitempty"`
+		Amount         *int            `json:"amount,omitempty"`
+		ClientIP       string          `json:"client_ip,omitempty"`
+		RequestID      string          `json:"request_id,omitempty"`
+		Details        json.RawMessage `json:"details,omitempty"`
+		CreateDateTime time.Time       `json:"created_at"`
+	}
+	IdempotencyKey struct {
+		UserID         int
+		Key            string
+		Fingerprint    string
+		StatusCode     int
+		Response       json.RawMessage
+		CreateDateTime time.Time
+	}
+	AuditFilter struct {
+		ActorID  *int
+		Action   string
+		Target   string
+		From     *time.Time
+		To       *time.Time
+		BeforeID int64
+		Limit    int
+	}
+	OrderFilter struct {
+		Statuses []OrderStatus
+		From     *time.Time
+		To       *time.Time
+		After    *PageKey
+		Limit    int
+	}
+	WithdrawalFilter struct {
+		From  *time.Time
+		To    *time.Time
+		After *PageKey
+		Limit int
+	}
+	PageKey struct {
+		Time time.Time
+		ID   string
+	}
+	OrderStatus      string
+	OutboxStatus     string
+	LedgerEntryType  string
+	WithdrawalStatus string
 )
 
 const (
@@ -35,6 +174,43 @@ const (
 	StatusProcessed  = "PROCESSED"
 )
 
+const (
+	OutboxPending   = "PENDING"
+	OutboxPublished = "PUBLISHED"
+	OutboxDead      = "DEAD"
+
+	EventOrderStatusChanged = "order.status_changed"
+
+	LedgerAccrual    = "ACCRUAL"
+	LedgerWithdrawal = "WITHDRAWAL"
+	LedgerAdjustment = "ADJUSTMENT"
+	LedgerReversal   = "REVERSAL"
+	LedgerExpiry     = "EXPIRY"
+	LedgerTransfer   = "TRANSFER"
+	LedgerBonus      = "BONUS"
+
+	ContraAccrual        = "accrual"
+	ContraWithdrawal     = "withdrawal"
+	ContraAdjustment     = "adjustment"
+	ContraReconciliation = "reconciliation"
+	ContraExpiry         = "expiry"
+	ContraTransfer       = "transfer"
+	ContraBonus          = "bonus"
+
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+
+	WithdrawalPending   = "PENDING"
+	WithdrawalCompleted = "COMPLETED"
+	WithdrawalReversed  = "REVERSED"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
 var statusToString = map[OrderStatus]string{
 	StatusNew:        "NEW",
 	StatusProcessing: "PROCESSING",
@@ -48,6 +224,16 @@ var stringToStatus = map[string]OrderStatus{
 	"PROCESSED":  StatusProcessed,
 }
 
+func ParseOrderStatus(status string) (OrderStatus, bool) {
+	for orderStatus, str := range statusToString {
+		if str == status {
+			return orderStatus, true
+		}
+	}
+
+	return "", false
+}
+
 func (o *Order) SetStatusFromStr(status string) {
 	if intStatus, exists := stringToStatus[status]; exists {
 		o.Status = intStatus
@@ -94,3 +280,57 @@ func (w *Withdrawal) MarshalJSON() ([]byte, error) {
 		Alias:      (*Alias)(w),
 	})
 }
+
+func (l *LedgerEntry) MarshalJSON() ([]byte, error) {
+	type Alias LedgerEntry
+	return json.Marshal(&struct {
+		Amount       float64 `json:"amount"`
+		BalanceAfter float64 `json:"balance"`
+		*Alias
+	}{
+		Amount:       float64(l.Amount) / 100,
+		BalanceAfter: float64(l.BalanceAfter) / 100,
+		Alias:        (*Alias)(l),
+	})
+}
+
+func (d *Discrepancy) Difference() int {
+	return d.Expected - d.Balance
+}
+
+func (d *Discrepancy) MarshalJSON() ([]byte, error) {
+	type Alias Discrepancy
+	return json.Marshal(&struct {
+		Balance    float64 `json:"balance"`
+		Expected   float64 `json:"expected"`
+		Difference float64 `json:"difference"`
+		*Alias
+	}{
+		Balance:    float64(d.Balance) / 100,
+		Expected:   float64(d.Expected) / 100,
+		Difference: float64(d.Difference()) / 100,
+		Alias:      (*Alias)(d),
+	})
+}
+
+func NewOrderStatusEvent(order *Order, userID int) (*OutboxEvent, error) {
+	payload := OrderStatusPayload{
+		Order:  order.ID,
+		UserID: userID,
+		Status: order.Status,
+	}
+	if order.Accrual != nil {
+		payload.Accrual = float64(*order.Accrual) / 100
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEvent{
+		EventType:   EventOrderStatusChanged,
+		AggregateID: order.ID,
+		Payload:     body,
+	}, nil
+}
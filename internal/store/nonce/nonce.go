@@ -0,0 +1,44 @@
+package nonce
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+)
+
+const (
+	deleteExpiredNonceSQL = `DELETE FROM public.partner_nonce WHERE expires_at <= NOW()`
+	insertNonceSQL        = `INSERT INTO public.partner_nonce (signature, expires_at) VALUES ($1, $2) ON CONFLICT (signature) DO NOTHING`
+)
+
+type Nonce struct {
+	dbpool *pgretry.PgxRetry
+	log    *zap.SugaredLogger
+}
+
+func NewNonce(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *Nonce {
+	return &Nonce{
+		dbpool: dbpool,
+		log:    log,
+	}
+}
+
+// Use запоминает подпись до expiresAt и возвращает false, если она уже использовалась.
+// Просроченные подписи удаляются здесь же, поэтому отдельная очистка таблицы не нужна.
+func (n *Nonce) Use(ctx context.Context, signature string, expiresAt time.Time) (bool, error) {
+	_, err := n.dbpool.Exec(ctx, deleteExpiredNonceSQL)
+	if err != nil {
+		n.log.Errorf("ошибка при удалении просроченных подписей: %v", err)
+	}
+
+	tag, err := n.dbpool.Exec(ctx, insertNonceSQL, signature, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при сохранении подписи: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
@@ -4,75 +4,74 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
-	"go.uber.org/fx"
 	"go.uber.org/zap"
 
+	"github.com/dontagr/loyalty/internal/config"
 	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
 	"github.com/dontagr/loyalty/internal/store/models"
+	"github.com/dontagr/loyalty/internal/tracing"
 )
 
 const (
 	searchOrderSQL                 = `SELECT id, user_id, status, accrual, create_dt FROM public.order WHERE id=$1`
-	insertOrderSQL                 = `INSERT INTO public.order (id, user_id) VALUES ($1, $2);`
-	updateOrderStatusSQL           = `UPDATE public.order SET status=$1 WHERE id=$2;`
-	updateOrderStatusAndAccrualSQL = `UPDATE public.order SET status=$1, accrual=$2 WHERE id=$3;`
+	insertOrderSQL                 = `INSERT INTO public.order (id, user_id, trace_parent) VALUES ($1, $2, NULLIF($3, ''));`
+	updateOrderStatusSQL           = `UPDATE public.order SET status=$1 WHERE id=$2 AND status IN ('NEW', 'PROCESSING');`
+	updateOrderStatusAndAccrualSQL = `UPDATE public.order SET status=$1, accrual=$2 WHERE id=$3 AND status IN ('NEW', 'PROCESSING');`
 	listOrderSQL                   = `SELECT id, user_id, status, accrual, create_dt  FROM public.order WHERE user_id = $1 ORDER BY create_dt DESC`
-	listOrderForProcessingSQL      = `SELECT id  FROM public.order WHERE status IN ('NEW', 'PROCESSING') AND block != true`
-	increaseUserBalanceSQL         = `UPDATE public.user SET balance=balance+$1 WHERE ID=$2`
-	selectOrderBlockSQL            = `SELECT block FROM public.order WHERE ID=$1 FOR UPDATE`
-	updateOrderBlockSQL            = `UPDATE public.order SET block=$1 WHERE ID=$2`
-	createOrderTable               = `
-DO $$
-BEGIN
-IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'statuses') THEN
-	CREATE TYPE statuses AS ENUM ('NEW', 'PROCESSING', 'INVALID', 'PROCESSED');
-END IF;
-
-CREATE TABLE IF NOT EXISTS public."order" (
-	id bigint NOT NULL,
-	user_id bigint NOT NULL,
-	accrual bigint DEFAULT NUll,
-	status statuses DEFAULT 'NEW' NOT NULL,
-	create_dt timestamptz DEFAULT NOW() NOT NULL,
-    block bool DEFAULT false NOT NULL,
-	CONSTRAINT order_pk PRIMARY KEY (id),
-	CONSTRAINT order_id_idx UNIQUE (user_id,id)
-);
-END$$;
-`
+	listOrderPageSQL               = `SELECT id, user_id, status, accrual, create_dt FROM public.order WHERE user_id = $1`
+	claimOrderForProcessingSQL     = `
+WITH claimed AS (
+	UPDATE public.order o SET lease_owner=$1, lease_expires_at=NOW() + $2 * interval '1 second'
+	FROM (
+		SELECT id FROM public.order
+		WHERE status IN ('NEW', 'PROCESSING') AND stalled_at IS NULL AND next_attempt_at <= NOW()
+			AND (lease_expires_at IS NULL OR lease_expires_at <= NOW())
+		ORDER BY next_attempt_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	) due
+	WHERE o.id = due.id
+	RETURNING o.id, o.attempt_count, o.trace_parent, o.next_attempt_at
+)
+SELECT id, attempt_count, COALESCE(trace_parent, '') FROM claimed ORDER BY next_attempt_at`
+	releaseOrderLeaseSQL  = `UPDATE public.order SET lease_owner=NULL, lease_expires_at=NULL WHERE id=$1 AND lease_owner=$2`
+	updateOrderAttemptSQL = `UPDATE public.order SET attempt_count=attempt_count+1, last_polled_at=NOW(), next_attempt_at=$1 WHERE id=$2`
+	updateOrderStalledSQL = `UPDATE public.order SET stalled_at=NOW() WHERE status IN ('NEW', 'PROCESSING') AND stalled_at IS NULL AND create_dt < $1`
+	resetOrderSQL         = `
+UPDATE public.order SET status='NEW', attempt_count=0, next_attempt_at=NOW(), stalled_at=NULL, lease_owner=NULL, lease_expires_at=NULL
+WHERE id=$1 AND status <> 'PROCESSED'`
+	searchUserTierSQL = `SELECT COALESCE(tier, '') FROM public.user WHERE id=$1`
 )
 
 type Order struct {
-	dbpool *pgretry.PgxRetry
-	log    *zap.SugaredLogger
+	dbpool  *pgretry.PgxRetry
+	outbox  interfaces.OutboxStore
+	ledger  interfaces.LedgerStore
+	loyalty *config.Loyalty
+	log     *zap.SugaredLogger
 }
 
-func NewOrder(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry, lc fx.Lifecycle) *Order {
+func NewOrder(cfg *config.Config, log *zap.SugaredLogger, dbpool *pgretry.PgxRetry, outbox interfaces.OutboxStore, ledger interfaces.LedgerStore) *Order {
 	order := Order{
-		dbpool: dbpool,
-		log:    log,
+		dbpool:  dbpool,
+		outbox:  outbox,
+		ledger:  ledger,
+		loyalty: &cfg.Loyalty,
+		log:     log,
 	}
 
-	lc.Append(fx.Hook{
-		OnStart: func(ctx context.Context) error {
-			return order.addShema(ctx)
-		},
-	})
-
 	return &order
 }
 
-func (o *Order) addShema(ctx context.Context) error {
-	_, err := o.dbpool.Exec(ctx, createOrderTable)
-
-	return err
-}
-
-func (o *Order) GetOrder(orderID string) (*models.Order, error) {
+func (o *Order) GetOrder(ctx context.Context, orderID string) (*models.Order, error) {
 	var order models.Order
-	err := o.dbpool.QueryRow(context.Background(), searchOrderSQL, orderID).Scan(
+	err := o.dbpool.QueryRow(ctx, searchOrderSQL, orderID).Scan(
 		&order.ID,
 		&order.UserID,
 		&order.Status,
@@ -89,8 +88,9 @@ func (o *Order) GetOrder(orderID string) (*models.Order, error) {
 	return &order, nil
 }
 
-func (o *Order) SaveOrder(orderID string, userID int) error {
-	_, err := o.dbpool.Exec(context.Background(), insertOrderSQL, orderID, userID)
+// SaveOrder сохраняет traceparent запроса загрузки, чтобы спаны опроса заказа ссылались на него.
+func (o *Order) SaveOrder(ctx context.Context, orderID string, userID int) error {
+	_, err := o.dbpool.Exec(ctx, insertOrderSQL, orderID, userID, tracing.TraceParent(ctx))
 	if err != nil {
 		return fmt.Errorf("ошибка при сохранении заказа: %w", err)
 	}
@@ -98,8 +98,8 @@ func (o *Order) SaveOrder(orderID string, userID int) error {
 	return nil
 }
 
-func (o *Order) GetListByUserID(userID int) ([]*models.Order, error) {
-	rows, err := o.dbpool.Query(context.Background(), listOrderSQL, userID)
+func (o *Order) GetListByUserID(ctx context.Context, userID int) ([]*models.Order, error) {
+	rows, err := o.dbpool.Query(ctx, listOrderSQL, userID)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при извлечении заказов: %w", err)
 	}
@@ -119,17 +119,43 @@ func (o *Order) GetListByUserID(userID int) ([]*models.Order, error) {
 	return result, nil
 }
 
-func (o *Order) GetListForProcessing() ([]*models.Order, error) {
-	rows, err := o.dbpool.Query(context.Background(), listOrderForProcessingSQL)
+func (o *Order) GetPageByUserID(ctx context.Context, userID int, filter *models.OrderFilter) ([]*models.Order, error) {
+	query := strings.Builder{}
+	query.WriteString(listOrderPageSQL)
+	args := []any{userID}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, status.String())
+		}
+		args = append(args, statuses)
+		fmt.Fprintf(&query, " AND status::text = ANY($%d::text[])", len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		fmt.Fprintf(&query, " AND create_dt >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		fmt.Fprintf(&query, " AND create_dt < $%d", len(args))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.Time, filter.After.ID)
+		fmt.Fprintf(&query, " AND (create_dt, id) < ($%d, $%d::bigint)", len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	fmt.Fprintf(&query, " ORDER BY create_dt DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := o.dbpool.Query(ctx, query.String(), args...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при извлечении заказов: %w", err)
 	}
 	defer rows.Close()
 
-	var result []*models.Order
+	result := make([]*models.Order, 0, filter.Limit)
 	for rows.Next() {
 		order := new(models.Order)
-		err := rows.Scan(&order.ID)
+		err := rows.Scan(&order.ID, &order.UserID, &order.Status, &order.Accrual, &order.CreateDateTime)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка при сканировании заказа: %w", err)
 		}
@@ -140,114 +166,190 @@ func (o *Order) GetListForProcessing() ([]*models.Order, error) {
 	return result, nil
 }
 
-func (o *Order) UpdateOrder(order *models.Order) error {
-	oldOrder, err := o.GetOrder(order.ID)
+func (o *Order) ClaimForProcessing(ctx context.Context, owner string, leaseTTL time.Duration, limit int) ([]*models.Order, error) {
+	rows, err := o.dbpool.Query(ctx, claimOrderForProcessingSQL, owner, leaseTTL.Seconds(), limit)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("ошибка при извлечении заказов: %w", err)
 	}
+	defer rows.Close()
 
-	if order.Status == models.StatusProcessing && oldOrder.Status != models.StatusInvalid && oldOrder.Status != models.StatusProcessed {
-		_, err := o.dbpool.Exec(context.Background(), updateOrderStatusSQL, order.Status, order.ID)
+	var result []*models.Order
+	for rows.Next() {
+		order := new(models.Order)
+		err := rows.Scan(&order.ID, &order.AttemptCount, &order.TraceParent)
 		if err != nil {
-			return fmt.Errorf("ошибка при обновлении заказа: %w", err)
+			return nil, fmt.Errorf("ошибка при сканировании заказа: %w", err)
 		}
 
-		return nil
+		result = append(result, order)
+	}
+
+	return result, nil
+}
+
+func (o *Order) ScheduleNextAttempt(ctx context.Context, orderID string, nextAttempt time.Time) error {
+	_, err := o.dbpool.Exec(ctx, updateOrderAttemptSQL, nextAttempt, orderID)
+	if err != nil {
+		return fmt.Errorf("ошибка при планировании опроса заказа: %w", err)
+	}
+
+	return nil
+}
+
+func (o *Order) ReleaseLease(ctx context.Context, orderID string, owner string) error {
+	_, err := o.dbpool.Exec(ctx, releaseOrderLeaseSQL, orderID, owner)
+	if err != nil {
+		return fmt.Errorf("ошибка при освобождении заказа: %w", err)
+	}
+
+	return nil
+}
+
+func (o *Order) MarkStalled(ctx context.Context, createdBefore time.Time) (int64, error) {
+	tag, err := o.dbpool.Exec(ctx, updateOrderStalledSQL, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при пометке зависших заказов: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ResetForPolling возвращает заказ в NEW и снимает аренду и пометку о зависании. Обработанные заказы
+// не сбрасываются, иначе повторный опрос начислит баллы второй раз.
+func (o *Order) ResetForPolling(ctx context.Context, orderID string) (bool, error) {
+	tag, err := o.dbpool.Exec(ctx, resetOrderSQL, orderID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при сбросе заказа: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UpdateOrder возвращает число баллов, начисленных пользователю вместе с надбавкой уровня.
+// credited вызывается в той же транзакции, только если баллы действительно начислены.
+func (o *Order) UpdateOrder(ctx context.Context, order *models.Order, credited func(tx pgx.Tx, amount int) error) (int, error) {
+	oldOrder, err := o.GetOrder(ctx, order.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if order.Status == models.StatusProcessing && oldOrder.Status != models.StatusInvalid && oldOrder.Status != models.StatusProcessed {
+		return 0, o.updateTx(ctx, order, oldOrder, func(tx pgx.Tx) (bool, error) {
+			tag, err := tx.Exec(ctx, updateOrderStatusSQL, order.Status, order.ID)
+			if err != nil {
+				return false, fmt.Errorf("ошибка при обновлении заказа: %w", err)
+			}
+
+			return tag.RowsAffected() > 0, nil
+		})
 	}
 
 	if order.Status == models.StatusInvalid {
-		_, err := o.dbpool.Exec(context.Background(), updateOrderStatusSQL, order.Status, order.ID)
-		if err != nil {
-			return fmt.Errorf("ошибка при обновлении заказа: %w", err)
-		}
+		return 0, o.updateTx(ctx, order, oldOrder, func(tx pgx.Tx) (bool, error) {
+			tag, err := tx.Exec(ctx, updateOrderStatusSQL, order.Status, order.ID)
+			if err != nil {
+				return false, fmt.Errorf("ошибка при обновлении заказа: %w", err)
+			}
 
-		return nil
+			return tag.RowsAffected() > 0, nil
+		})
 	}
 
 	if order.Status == models.StatusProcessed && *order.Accrual > 0 {
-		tx, txErr := o.dbpool.Begin(context.Background())
-		if txErr != nil {
-			return fmt.Errorf("ошибка начала транзакции: %w", txErr)
-		}
-		defer func(txErr *error) {
-			if *txErr != nil {
-				if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
-					o.log.Errorf("ошибка отката транзакции: %v", rollbackErr)
-				}
-			} else {
-				if commitErr := tx.Commit(context.Background()); commitErr != nil {
-					o.log.Errorf("ошибка при коммите транзакции: %v", commitErr)
+		total := 0
+		err = o.updateTx(ctx, order, oldOrder, func(tx pgx.Tx) (bool, error) {
+			tag, err := tx.Exec(ctx, updateOrderStatusAndAccrualSQL, order.Status, order.Accrual, order.ID)
+			if err != nil {
+				return false, fmt.Errorf("ошибка при обновлении заказа: %w", err)
+			}
+			// заказ уже обработан другим воркером, повторно баллы не начисляем
+			if tag.RowsAffected() == 0 {
+				return false, nil
+			}
+
+			err = o.ledger.PostTx(ctx, tx, &models.LedgerEntry{
+				UserID:        oldOrder.UserID,
+				EntryType:     models.LedgerAccrual,
+				ContraAccount: models.ContraAccrual,
+				Amount:        *order.Accrual,
+				OrderID:       &order.ID,
+			})
+			if err != nil {
+				return false, err
+			}
+
+			bonus, err := o.postBonusTx(ctx, tx, oldOrder.UserID, order)
+			if err != nil {
+				return false, err
+			}
+			total = *order.Accrual + bonus
+			if credited != nil {
+				if err := credited(tx, total); err != nil {
+					return false, err
 				}
 			}
-		}(&txErr)
 
-		_, err := tx.Exec(context.Background(), updateOrderStatusAndAccrualSQL, order.Status, order.Accrual, order.ID)
-		if err != nil {
-			txErr = err
-			return fmt.Errorf("ошибка при обновлении заказа: %w", err)
-		}
-		_, err = tx.Exec(context.Background(), increaseUserBalanceSQL, order.Accrual, oldOrder.UserID)
+			return true, nil
+		})
 		if err != nil {
-			txErr = err
-			return fmt.Errorf("ошибка при обновлении пользователя: %w", err)
+			return 0, err
 		}
 
-		return nil
+		return total, nil
 	}
 
-	return fmt.Errorf("update order has failed order %v", order)
+	return 0, fmt.Errorf("update order has failed order %v", order)
 }
 
-func (o *Order) BlockOrder(orderID string) bool {
-	tx, txErr := o.dbpool.Begin(context.Background())
-	if txErr != nil {
-		o.log.Errorf("ошибка начала транзакции: %w", txErr)
+// postBonusTx начисляет надбавку по множителю уровня отдельной проводкой, чтобы в заказе
+// и в сверке оставалось начисление системы расчета. Строка пользователя уже заблокирована проводкой ACCRUAL.
+func (o *Order) postBonusTx(ctx context.Context, tx pgx.Tx, userID int, order *models.Order) (int, error) {
+	var name string
+	err := tx.QueryRow(ctx, searchUserTierSQL, userID).Scan(&name)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении уровня пользователя: %w", err)
 	}
-	defer func(txErr *error) {
-		if *txErr != nil {
-			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
-				o.log.Errorf("ошибка отката транзакции: %v", rollbackErr)
-			}
-		} else {
-			if commitErr := tx.Commit(context.Background()); commitErr != nil {
-				o.log.Errorf("ошибка при коммите транзакции: %v", commitErr)
-			}
-		}
-	}(&txErr)
 
-	var block bool
-	err := tx.QueryRow(context.Background(), selectOrderBlockSQL, orderID).Scan(&block)
-	if err != nil || block {
-		return false
+	tier := o.loyalty.Tier(name)
+	if tier == nil {
+		return 0, nil
+	}
+	bonus := int(math.Round(float64(*order.Accrual) * (tier.Multiplier - 1)))
+	if bonus <= 0 {
+		return 0, nil
 	}
 
-	_, err = tx.Exec(context.Background(), updateOrderBlockSQL, "TRUE", orderID)
-	return err == nil
+	reason := tier.Name
+	err = o.ledger.PostTx(ctx, tx, &models.LedgerEntry{
+		UserID:        userID,
+		EntryType:     models.LedgerBonus,
+		ContraAccount: models.ContraBonus,
+		Amount:        bonus,
+		OrderID:       &order.ID,
+		Reason:        &reason,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return bonus, nil
 }
 
-func (o *Order) UnblockOrder(orderID string) bool {
-	tx, txErr := o.dbpool.Begin(context.Background())
-	if txErr != nil {
-		o.log.Errorf("ошибка начала транзакции: %w", txErr)
-	}
-	defer func(txErr *error) {
-		if *txErr != nil {
-			if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
-				o.log.Errorf("ошибка отката транзакции: %v", rollbackErr)
-			}
-		} else {
-			if commitErr := tx.Commit(context.Background()); commitErr != nil {
-				o.log.Errorf("ошибка при коммите транзакции: %v", commitErr)
-			}
+func (o *Order) updateTx(ctx context.Context, order *models.Order, oldOrder *models.Order, update func(tx pgx.Tx) (bool, error)) error {
+	return o.dbpool.RunInTx(ctx, func(tx pgx.Tx) error {
+		changed, err := update(tx)
+		if err != nil {
+			return err
+		}
+		if !changed || oldOrder.Status == order.Status {
+			return nil
 		}
-	}(&txErr)
 
-	var block bool
-	err := tx.QueryRow(context.Background(), selectOrderBlockSQL, orderID).Scan(&block)
-	if err != nil || !block {
-		return false
-	}
+		event, err := models.NewOrderStatusEvent(order, oldOrder.UserID)
+		if err != nil {
+			return fmt.Errorf("ошибка при формировании события: %w", err)
+		}
 
-	_, err = tx.Exec(context.Background(), updateOrderBlockSQL, "FALSE", orderID)
-	return err == nil
+		return o.outbox.AddTx(ctx, tx, event)
+	})
 }
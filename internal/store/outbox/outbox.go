@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	insertEventSQL       = `INSERT INTO public.outbox (event_type, aggregate_id, payload) VALUES ($1, $2, $3);`
+	claimPendingEventSQL = `
+WITH claimed AS (
+	UPDATE public.outbox o SET lease_owner=$1, lease_expires_at=NOW() + $2 * interval '1 second'
+	FROM (
+		SELECT id FROM public.outbox
+		WHERE status = 'PENDING' AND next_attempt_dt <= NOW()
+			AND (lease_expires_at IS NULL OR lease_expires_at <= NOW())
+		ORDER BY next_attempt_dt, id
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	) due
+	WHERE o.id = due.id
+	RETURNING o.id, o.event_type, o.aggregate_id, o.payload, o.status, o.attempts, o.create_dt, o.next_attempt_dt
+)
+SELECT id, event_type, aggregate_id, payload, status, attempts, create_dt FROM claimed ORDER BY next_attempt_dt, id`
+	markPublishedSQL = `UPDATE public.outbox SET status='PUBLISHED', attempts=attempts+1, last_error=NULL, publish_dt=NOW(), lease_owner=NULL, lease_expires_at=NULL WHERE id=$1`
+	markFailedSQL    = `UPDATE public.outbox SET status=$1, attempts=$2, last_error=$3, next_attempt_dt=$4, lease_owner=NULL, lease_expires_at=NULL WHERE id=$5`
+)
+
+type Outbox struct {
+	dbpool *pgretry.PgxRetry
+	log    *zap.SugaredLogger
+}
+
+func NewOutbox(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *Outbox {
+	return &Outbox{
+		dbpool: dbpool,
+		log:    log,
+	}
+}
+
+func (o *Outbox) AddTx(ctx context.Context, tx pgx.Tx, event *models.OutboxEvent) error {
+	_, err := tx.Exec(ctx, insertEventSQL, event.EventType, event.AggregateID, event.Payload)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении события: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimPending берет в аренду готовые к отправке события, чтобы реплики не публиковали одни и те же.
+// Если реплика упала, не отметив событие, его заберет другая после истечения аренды.
+func (o *Outbox) ClaimPending(ctx context.Context, owner string, leaseTTL time.Duration, limit int) ([]*models.OutboxEvent, error) {
+	rows, err := o.dbpool.Query(ctx, claimPendingEventSQL, owner, leaseTTL.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при извлечении событий: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.OutboxEvent
+	for rows.Next() {
+		event := new(models.OutboxEvent)
+		err := rows.Scan(&event.ID, &event.EventType, &event.AggregateID, &event.Payload, &event.Status, &event.Attempts, &event.CreateDateTime)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании события: %w", err)
+		}
+
+		result = append(result, event)
+	}
+
+	return result, nil
+}
+
+func (o *Outbox) MarkPublished(ctx context.Context, eventID int64) error {
+	_, err := o.dbpool.Exec(ctx, markPublishedSQL, eventID)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении события: %w", err)
+	}
+
+	return nil
+}
+
+func (o *Outbox) MarkFailed(ctx context.Context, event *models.OutboxEvent, lastError string, nextAttempt time.Time) error {
+	_, err := o.dbpool.Exec(ctx, markFailedSQL, event.Status, event.Attempts, lastError, nextAttempt, event.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении события: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+)
+
+const (
+	insertEventSQL = `
+WITH ins AS (INSERT INTO public.rate_limit_event (key, create_dt) VALUES ($1, $2))
+SELECT COUNT(*) + 1 FROM public.rate_limit_event WHERE key=$1 AND create_dt > $3`
+	deleteOldEventSQL = `DELETE FROM public.rate_limit_event WHERE key=$1 AND create_dt <= $2`
+	deleteEventSQL    = `DELETE FROM public.rate_limit_event WHERE key=$1`
+	upsertLockSQL     = `
+INSERT INTO public.rate_limit_lock (key, locked_until) VALUES ($1, $2)
+ON CONFLICT (key) DO UPDATE SET locked_until = GREATEST(public.rate_limit_lock.locked_until, EXCLUDED.locked_until)`
+	searchLockSQL         = `SELECT locked_until FROM public.rate_limit_lock WHERE key=$1 AND locked_until > $2`
+	deleteLockSQL         = `DELETE FROM public.rate_limit_lock WHERE key=$1`
+	deleteExpiredEventSQL = `DELETE FROM public.rate_limit_event WHERE create_dt <= $1`
+	deleteExpiredLockSQL  = `DELETE FROM public.rate_limit_lock WHERE locked_until <= $1`
+)
+
+type RateLimit struct {
+	dbpool *pgretry.PgxRetry
+	log    *zap.SugaredLogger
+}
+
+func NewRateLimit(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *RateLimit {
+	return &RateLimit{
+		dbpool: dbpool,
+		log:    log,
+	}
+}
+
+func (r *RateLimit) AddFailure(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	var count int
+	err := r.dbpool.QueryRow(ctx, insertEventSQL, key, now, now.Add(-window)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при сохранении неудачной попытки: %w", err)
+	}
+
+	_, err = r.dbpool.Exec(ctx, deleteOldEventSQL, key, now.Add(-window))
+	if err != nil {
+		r.log.Errorf("ошибка при удалении устаревших попыток: %v", err)
+	}
+
+	return count, nil
+}
+
+func (r *RateLimit) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := r.dbpool.Exec(ctx, upsertLockSQL, key, until)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении блокировки: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RateLimit) GetLock(ctx context.Context, key string, now time.Time) (time.Time, error) {
+	var until time.Time
+	err := r.dbpool.QueryRow(ctx, searchLockSQL, key, now).Scan(&until)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ошибка при поиске блокировки: %w", err)
+	}
+
+	return until, nil
+}
+
+// DeleteExpired удаляет попытки старше before и истекшие блокировки всех ключей: AddFailure чистит
+// только свой ключ, и без этого каждый адрес или логин с неудачной попыткой оставался бы в таблицах.
+func (r *RateLimit) DeleteExpired(ctx context.Context, before time.Time, now time.Time) (int64, error) {
+	events, err := r.dbpool.Exec(ctx, deleteExpiredEventSQL, before)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении устаревших попыток: %w", err)
+	}
+
+	locks, err := r.dbpool.Exec(ctx, deleteExpiredLockSQL, now)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении истекших блокировок: %w", err)
+	}
+
+	return events.RowsAffected() + locks.RowsAffected(), nil
+}
+
+func (r *RateLimit) Reset(ctx context.Context, key string) error {
+	_, err := r.dbpool.Exec(ctx, deleteEventSQL, key)
+	if err != nil {
+		return fmt.Errorf("ошибка при сбросе попыток: %w", err)
+	}
+
+	_, err = r.dbpool.Exec(ctx, deleteLockSQL, key)
+	if err != nil {
+		return fmt.Errorf("ошибка при снятии блокировки: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	countUserSQL       = `SELECT COUNT(*) FROM public.user`
+	lockUserSQL        = `SELECT id FROM public.user WHERE id=$1 FOR UPDATE`
+	expectedBalanceSQL = `
+SELECT u.id, u.login, u.balance, COALESCE(o.total, 0) - COALESCE(w.total, 0) + COALESCE(l.total, 0) AS expected
+FROM public.user u
+LEFT JOIN (SELECT user_id, SUM(accrual) AS total FROM public.order WHERE status = 'PROCESSED' AND accrual IS NOT NULL GROUP BY user_id) o ON o.user_id = u.id
+LEFT JOIN (SELECT user_id, SUM(withdrawal) AS total FROM public.withdrawal GROUP BY user_id) w ON w.user_id = u.id
+LEFT JOIN (
+	SELECT user_id, SUM(amount) AS total FROM public.ledger
+	WHERE user_id IS NOT NULL AND entry_type NOT IN ('ACCRUAL', 'WITHDRAWAL') AND contra_account NOT IN ('reconciliation', 'opening')
+	GROUP BY user_id
+) l ON l.user_id = u.id
+WHERE ($1::bigint = 0 OR u.id = $1::bigint)
+	AND u.balance <> COALESCE(o.total, 0) - COALESCE(w.total, 0) + COALESCE(l.total, 0)
+ORDER BY u.id`
+	insertRunSQL          = `INSERT INTO public.reconciliation_run (auto_correct) VALUES ($1) RETURNING id, start_dt`
+	finishRunSQL          = `UPDATE public.reconciliation_run SET users_checked=$1, discrepancies=$2, corrected=$3, finish_dt=NOW() WHERE id=$4 RETURNING finish_dt`
+	insertDiscrepancySQL  = `INSERT INTO public.reconciliation_discrepancy (run_id, user_id, balance, expected, corrected) VALUES ($1, $2, $3, $4, $5) RETURNING id, create_dt`
+	listRunSQL            = `SELECT id, auto_correct, users_checked, corrected, start_dt, finish_dt FROM public.reconciliation_run ORDER BY id DESC LIMIT $1`
+	listDiscrepancyRunSQL = `SELECT d.id, d.run_id, d.user_id, u.login, d.balance, d.expected, d.corrected, d.create_dt FROM public.reconciliation_discrepancy d JOIN public.user u ON u.id = d.user_id WHERE d.run_id = ANY($1) ORDER BY d.id`
+)
+
+type Reconciliation struct {
+	dbpool *pgretry.PgxRetry
+	ledger interfaces.LedgerStore
+	log    *zap.SugaredLogger
+}
+
+func NewReconciliation(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry, ledger interfaces.LedgerStore) *Reconciliation {
+	return &Reconciliation{
+		dbpool: dbpool,
+		ledger: ledger,
+		log:    log,
+	}
+}
+
+func (r *Reconciliation) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	err := r.dbpool.QueryRow(ctx, countUserSQL).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при подсчете пользователей: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *Reconciliation) GetDiscrepancies(ctx context.Context) ([]*models.Discrepancy, error) {
+	rows, err := r.dbpool.Query(ctx, expectedBalanceSQL, 0)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при расчете балансов: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.Discrepancy
+	for rows.Next() {
+		discrepancy := new(models.Discrepancy)
+		err := rows.Scan(&discrepancy.UserID, &discrepancy.Login, &discrepancy.Balance, &discrepancy.Expected)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании баланса: %w", err)
+		}
+
+		result = append(result, discrepancy)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Reconciliation) Correct(ctx context.Context, discrepancy *models.Discrepancy, reason string) error {
+	var current models.Discrepancy
+	corrected := false
+	err := r.dbpool.RunInTx(ctx, func(tx pgx.Tx) error {
+		corrected = false
+		var userID int
+		err := tx.QueryRow(ctx, lockUserSQL, discrepancy.UserID).Scan(&userID)
+		if err != nil {
+			return fmt.Errorf("ошибка при блокировке пользователя: %w", err)
+		}
+
+		// баланс мог измениться после общего расчета, поэтому пересчитываем под блокировкой
+		err = tx.QueryRow(ctx, expectedBalanceSQL, discrepancy.UserID).Scan(&current.UserID, &current.Login, &current.Balance, &current.Expected)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при расчете баланса: %w", err)
+		}
+
+		err = r.ledger.PostTx(ctx, tx, &models.LedgerEntry{
+			UserID:        current.UserID,
+			EntryType:     models.LedgerAdjustment,
+			ContraAccount: models.ContraReconciliation,
+			Amount:        current.Difference(),
+			Reason:        &reason,
+		})
+		if err != nil {
+			return err
+		}
+		corrected = true
+
+		return nil
+	})
+	if err != nil || !corrected {
+		return err
+	}
+
+	discrepancy.Balance = current.Balance
+	discrepancy.Expected = current.Expected
+	discrepancy.Corrected = true
+
+	return nil
+}
+
+func (r *Reconciliation) CreateRun(ctx context.Context, run *models.ReconciliationRun) error {
+	err := r.dbpool.QueryRow(ctx, insertRunSQL, run.AutoCorrect).Scan(&run.ID, &run.StartDateTime)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании сверки: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Reconciliation) SaveDiscrepancy(ctx context.Context, discrepancy *models.Discrepancy) error {
+	err := r.dbpool.QueryRow(
+		ctx,
+		insertDiscrepancySQL,
+		discrepancy.RunID,
+		discrepancy.UserID,
+		discrepancy.Balance,
+		discrepancy.Expected,
+		discrepancy.Corrected,
+	).Scan(&discrepancy.ID, &discrepancy.CreateDateTime)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении расхождения: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Reconciliation) FinishRun(ctx context.Context, run *models.ReconciliationRun) error {
+	err := r.dbpool.QueryRow(
+		ctx,
+		finishRunSQL,
+		run.UsersChecked,
+		len(run.Discrepancies),
+		run.Corrected,
+		run.ID,
+	).Scan(&run.FinishDateTime)
+	if err != nil {
+		return fmt.Errorf("ошибка при завершении сверки: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Reconciliation) GetRuns(ctx context.Context, limit int) ([]*models.ReconciliationRun, error) {
+	rows, err := r.dbpool.Query(ctx, listRunSQL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при извлечении сверок: %w", err)
+	}
+
+	var result []*models.ReconciliationRun
+	for rows.Next() {
+		run := new(models.ReconciliationRun)
+		err := rows.Scan(&run.ID, &run.AutoCorrect, &run.UsersChecked, &run.Corrected, &run.StartDateTime, &run.FinishDateTime)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка при сканировании сверки: %w", err)
+		}
+
+		result = append(result, run)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при извлечении сверок: %w", err)
+	}
+
+	err = r.fillRunDiscrepancies(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// fillRunDiscrepancies загружает расхождения всех сверок одним запросом.
+func (r *Reconciliation) fillRunDiscrepancies(ctx context.Context, runs []*models.ReconciliationRun) error {
+	byID := make(map[int64]*models.ReconciliationRun, len(runs))
+	ids := make([]int64, 0, len(runs))
+	for _, run := range runs {
+		run.Discrepancies = make([]*models.Discrepancy, 0)
+		byID[run.ID] = run
+		ids = append(ids, run.ID)
+	}
+
+	rows, err := r.dbpool.Query(ctx, listDiscrepancyRunSQL, ids)
+	if err != nil {
+		return fmt.Errorf("ошибка при извлечении расхождений: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		discrepancy := new(models.Discrepancy)
+		err := rows.Scan(
+			&discrepancy.ID,
+			&discrepancy.RunID,
+			&discrepancy.UserID,
+			&discrepancy.Login,
+			&discrepancy.Balance,
+			&discrepancy.Expected,
+			&discrepancy.Corrected,
+			&discrepancy.CreateDateTime,
+		)
+		if err != nil {
+			return fmt.Errorf("ошибка при сканировании расхождения: %w", err)
+		}
+
+		run := byID[discrepancy.RunID]
+		run.Discrepancies = append(run.Discrepancies, discrepancy)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при извлечении расхождений: %w", err)
+	}
+
+	return nil
+}
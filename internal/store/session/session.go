@@ -0,0 +1,169 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	insertSessionSQL      = `INSERT INTO public.session (user_id, expires_dt) VALUES ($1, $2) RETURNING id`
+	insertRefreshTokenSQL = `INSERT INTO public.refresh_token (token_hash, session_id, expires_dt) VALUES ($1, $2, $3)`
+	searchRefreshTokenSQL = `
+SELECT s.id, s.user_id, u.login, u.role, u.blocked_at IS NOT NULL, s.expires_dt, s.revoke_dt, t.expires_dt, t.use_dt
+FROM public.refresh_token t
+JOIN public.session s ON s.id = t.session_id
+JOIN public.user u ON u.id = s.user_id
+WHERE t.token_hash = $1
+FOR UPDATE OF t, s`
+	useRefreshTokenSQL    = `UPDATE public.refresh_token SET use_dt=NOW() WHERE token_hash=$1`
+	extendSessionSQL      = `UPDATE public.session SET expires_dt=$1 WHERE id=$2`
+	revokeSessionSQL      = `UPDATE public.session SET revoke_dt=NOW() WHERE id=$1 AND revoke_dt IS NULL`
+	searchSessionSQL      = `SELECT revoke_dt IS NOT NULL OR expires_dt <= NOW() FROM public.session WHERE id=$1`
+	revokeUserSessionsSQL = `UPDATE public.session SET revoke_dt=NOW() WHERE user_id=$1 AND revoke_dt IS NULL RETURNING id`
+)
+
+type Session struct {
+	dbpool *pgretry.PgxRetry
+	log    *zap.SugaredLogger
+}
+
+func NewSession(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *Session {
+	return &Session{
+		dbpool: dbpool,
+		log:    log,
+	}
+}
+
+func (s *Session) CreateSession(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) (int64, error) {
+	var sessionID int64
+	err := s.dbpool.RunInTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, insertSessionSQL, userID, expiresAt).Scan(&sessionID)
+		if err != nil {
+			return fmt.Errorf("ошибка при создании сессии: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, insertRefreshTokenSQL, tokenHash, sessionID, expiresAt)
+		if err != nil {
+			return fmt.Errorf("ошибка при сохранении refresh-токена: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return sessionID, nil
+}
+
+func (s *Session) RotateRefreshToken(ctx context.Context, oldHash string, newHash string, expiresAt time.Time) (*models.Session, error) {
+	session := &models.Session{}
+	var reused bool
+	err := s.dbpool.RunInTx(ctx, func(tx pgx.Tx) error {
+		reused = false
+		var tokenExpiresAt time.Time
+		var usedAt *time.Time
+		var blocked bool
+		err := tx.QueryRow(ctx, searchRefreshTokenSQL, oldHash).Scan(
+			&session.ID,
+			&session.UserID,
+			&session.Login,
+			&session.Role,
+			&blocked,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+			&tokenExpiresAt,
+			&usedAt,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrRefreshTokenInvalid
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при поиске refresh-токена: %w", err)
+		}
+
+		if usedAt != nil {
+			// повторное предъявление уже использованного токена: считаем его украденным и закрываем сессию
+			reused = true
+			_, err = tx.Exec(ctx, revokeSessionSQL, session.ID)
+			if err != nil {
+				return fmt.Errorf("ошибка при отзыве сессии: %w", err)
+			}
+
+			return nil
+		}
+		if blocked || session.RevokedAt != nil || !tokenExpiresAt.After(time.Now()) {
+			return models.ErrRefreshTokenInvalid
+		}
+
+		if _, err = tx.Exec(ctx, useRefreshTokenSQL, oldHash); err != nil {
+			return fmt.Errorf("ошибка при обновлении refresh-токена: %w", err)
+		}
+		if _, err = tx.Exec(ctx, insertRefreshTokenSQL, newHash, session.ID, expiresAt); err != nil {
+			return fmt.Errorf("ошибка при сохранении refresh-токена: %w", err)
+		}
+		if _, err = tx.Exec(ctx, extendSessionSQL, expiresAt, session.ID); err != nil {
+			return fmt.Errorf("ошибка при продлении сессии: %w", err)
+		}
+		session.ExpiresAt = expiresAt
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return session, models.ErrRefreshTokenReused
+	}
+
+	return session, nil
+}
+
+func (s *Session) RevokeSession(ctx context.Context, sessionID int64) error {
+	_, err := s.dbpool.Exec(ctx, revokeSessionSQL, sessionID)
+	if err != nil {
+		return fmt.Errorf("ошибка при отзыве сессии: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Session) IsSessionRevoked(ctx context.Context, sessionID int64) (bool, error) {
+	var revoked bool
+	err := s.dbpool.QueryRow(ctx, searchSessionSQL, sessionID).Scan(&revoked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка при проверке сессии: %w", err)
+	}
+
+	return revoked, nil
+}
+
+func (s *Session) RevokeUserSessions(ctx context.Context, userID int) ([]int64, error) {
+	rows, err := s.dbpool.Query(ctx, revokeUserSessionsSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при отзыве сессий пользователя: %w", err)
+	}
+	defer rows.Close()
+
+	var result []int64
+	for rows.Next() {
+		var sessionID int64
+		if err := rows.Scan(&sessionID); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании сессии: %w", err)
+		}
+		result = append(result, sessionID)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,92 @@
+package tier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	searchUserTierSQL = `
+SELECT u.id, COALESCE(u.tier, ''), COALESCE((
+	SELECT SUM(l.amount) FROM public.ledger l
+	WHERE l.user_id = u.id AND l.entry_type = 'ACCRUAL' AND l.create_dt > $2
+), 0)
+FROM public.user u WHERE u.id = $1`
+	listUserTierSQL = `
+SELECT u.id, COALESCE(u.tier, ''), COALESCE(SUM(l.amount), 0)
+FROM public.user u
+LEFT JOIN public.ledger l ON l.user_id = u.id AND l.entry_type = 'ACCRUAL' AND l.create_dt > $2
+WHERE u.id > $1
+GROUP BY u.id
+ORDER BY u.id
+LIMIT $3`
+	updateUserTierSQL = `UPDATE public.user SET tier=$2, tier_dt=NOW() WHERE id=$1 AND tier IS DISTINCT FROM $2`
+)
+
+type Tier struct {
+	dbpool *pgretry.PgxRetry
+	log    *zap.SugaredLogger
+}
+
+func NewTier(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *Tier {
+	return &Tier{
+		dbpool: dbpool,
+		log:    log,
+	}
+}
+
+// GetUserTier возвращает сохраненный уровень пользователя и сумму начислений по заказам после since.
+// Надбавки по уровню в сумму не входят, иначе уровень поддерживал бы сам себя.
+func (t *Tier) GetUserTier(ctx context.Context, userID int, since time.Time) (*models.UserTier, error) {
+	var userTier models.UserTier
+	err := t.dbpool.QueryRow(ctx, searchUserTierSQL, userID, since).Scan(&userTier.UserID, &userTier.Tier, &userTier.Accrued)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &models.UserTier{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении уровня пользователя: %w", err)
+	}
+
+	return &userTier, nil
+}
+
+// GetList возвращает пачку пользователей с id больше afterID по возрастанию id.
+func (t *Tier) GetList(ctx context.Context, afterID int, since time.Time, limit int) ([]*models.UserTier, error) {
+	rows, err := t.dbpool.Query(ctx, listUserTierSQL, afterID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении уровней пользователей: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.UserTier
+	for rows.Next() {
+		var userTier models.UserTier
+		err = rows.Scan(&userTier.UserID, &userTier.Tier, &userTier.Accrued)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении уровня пользователя: %w", err)
+		}
+		result = append(result, &userTier)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении уровней пользователей: %w", err)
+	}
+
+	return result, nil
+}
+
+func (t *Tier) UpdateTier(ctx context.Context, userID int, tier string) (bool, error) {
+	tag, err := t.dbpool.Exec(ctx, updateUserTierSQL, userID, tier)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при обновлении уровня пользователя: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
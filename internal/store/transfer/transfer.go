@@ -0,0 +1,77 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	insertTransferSQL = `INSERT INTO public.transfer (from_user_id, to_user_id, amount) VALUES ($1, $2, $3) RETURNING id, create_dt`
+	searchSentSQL     = `SELECT COALESCE(SUM(amount), 0), COUNT(*) FROM public.transfer WHERE from_user_id = $1 AND create_dt >= $2`
+)
+
+type Transfer struct {
+	dbpool *pgretry.PgxRetry
+	ledger interfaces.LedgerStore
+	log    *zap.SugaredLogger
+}
+
+func NewTransfer(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry, ledger interfaces.LedgerStore) *Transfer {
+	return &Transfer{
+		dbpool: dbpool,
+		ledger: ledger,
+		log:    log,
+	}
+}
+
+func (t *Transfer) RunInTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return t.dbpool.RunInTx(ctx, fn)
+}
+
+// GetSentTx возвращает сумму и число переводов пользователя начиная с since. Вызывается под блокировкой
+// отправителя, чтобы параллельные переводы не обошли дневной лимит.
+func (t *Transfer) GetSentTx(ctx context.Context, tx pgx.Tx, userID int, since time.Time) (int, int, error) {
+	var amount, count int
+	err := tx.QueryRow(ctx, searchSentSQL, userID, since).Scan(&amount, &count)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ошибка при подсчете переводов: %w", err)
+	}
+
+	return amount, count, nil
+}
+
+// SaveTx сохраняет перевод и проводит его по счетам обоих пользователей: сначала списание у отправителя,
+// затем зачисление получателю, которое переносит лоты отправителя.
+func (t *Transfer) SaveTx(ctx context.Context, tx pgx.Tx, transfer *models.Transfer) error {
+	err := tx.QueryRow(ctx, insertTransferSQL, transfer.FromUserID, transfer.ToUserID, transfer.Amount).Scan(&transfer.ID, &transfer.CreateDateTime)
+	if err != nil {
+		return fmt.Errorf("ошибка при создании перевода: %w", err)
+	}
+
+	err = t.ledger.PostTx(ctx, tx, &models.LedgerEntry{
+		UserID:        transfer.FromUserID,
+		EntryType:     models.LedgerTransfer,
+		ContraAccount: models.ContraTransfer,
+		Amount:        -transfer.Amount,
+		TransferID:    &transfer.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.ledger.PostTx(ctx, tx, &models.LedgerEntry{
+		UserID:        transfer.ToUserID,
+		EntryType:     models.LedgerTransfer,
+		ContraAccount: models.ContraTransfer,
+		Amount:        transfer.Amount,
+		TransferID:    &transfer.ID,
+	})
+}
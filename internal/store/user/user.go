@@ -6,7 +6,6 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
-	"go.uber.org/fx"
 	"go.uber.org/zap"
 
 	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
@@ -14,19 +13,12 @@ import (
 )
 
 const (
-	searchUserSQL          = `SELECT id, login, password, balance FROM public.user WHERE login=$1`
-	searchUserForUpdateSQL = `SELECT id, login, password, balance FROM public.user WHERE login=$1 FOR UPDATE`
+	searchUserSQL          = `SELECT id, login, password, balance, role, blocked_at FROM public.user WHERE login=$1`
+	searchUserForUpdateSQL = `SELECT id, login, password, balance, role, blocked_at FROM public.user WHERE login=$1 FOR UPDATE`
 	insertUserSQL          = `INSERT INTO public.user (login, password) VALUES ($1, $2);`
-	createUserTable        = `
-CREATE TABLE IF NOT EXISTS public."user" (
-	id bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY,
-	login varchar(255) not null,
-	password VARCHAR(255) not null,
-    balance bigint NOT NULL DEFAULT 0,
-	CONSTRAINT user_login UNIQUE ("login"),
-	CONSTRAINT user_pk PRIMARY KEY ("id")
-);
-`
+	blockUserSQL           = `UPDATE public.user SET blocked_at=NOW(), blocked_reason=$2 WHERE id=$1`
+	unblockUserSQL         = `UPDATE public.user SET blocked_at=NULL, blocked_reason=NULL WHERE id=$1`
+	updateUserRoleSQL      = `UPDATE public.user SET role=$2 WHERE login=$1`
 )
 
 type User struct {
@@ -34,34 +26,24 @@ type User struct {
 	log    *zap.SugaredLogger
 }
 
-func NewUser(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry, lc fx.Lifecycle) *User {
+func NewUser(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry) *User {
 	user := User{
 		dbpool: dbpool,
 		log:    log,
 	}
 
-	lc.Append(fx.Hook{
-		OnStart: func(ctx context.Context) error {
-			return user.addShema(ctx)
-		},
-	})
-
 	return &user
 }
 
-func (u *User) addShema(ctx context.Context) error {
-	_, err := u.dbpool.Exec(ctx, createUserTable)
-
-	return err
-}
-
-func (u *User) GetTxUser(tx pgx.Tx, login string) (*models.User, error) {
+func (u *User) GetTxUser(ctx context.Context, tx pgx.Tx, login string) (*models.User, error) {
 	var user models.User
-	err := tx.QueryRow(context.Background(), searchUserForUpdateSQL, login).Scan(
+	err := tx.QueryRow(ctx, searchUserForUpdateSQL, login).Scan(
 		&user.ID,
 		&user.Login,
 		&user.PasswordHash,
 		&user.Balance,
+		&user.Role,
+		&user.BlockedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return &models.User{}, nil
@@ -73,13 +55,15 @@ func (u *User) GetTxUser(tx pgx.Tx, login string) (*models.User, error) {
 	return &user, nil
 }
 
-func (u *User) GetUser(login string) (*models.User, error) {
+func (u *User) GetUser(ctx context.Context, login string) (*models.User, error) {
 	var user models.User
-	err := u.dbpool.QueryRow(context.Background(), searchUserSQL, login).Scan(
+	err := u.dbpool.QueryRow(ctx, searchUserSQL, login).Scan(
 		&user.ID,
 		&user.Login,
 		&user.PasswordHash,
 		&user.Balance,
+		&user.Role,
+		&user.BlockedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return &models.User{}, nil
@@ -91,11 +75,38 @@ func (u *User) GetUser(login string) (*models.User, error) {
 	return &user, nil
 }
 
-func (u *User) SaveUser(login string, passwordHash string) error {
-	_, err := u.dbpool.Exec(context.Background(), insertUserSQL, login, passwordHash)
+func (u *User) SaveUser(ctx context.Context, login string, passwordHash string) error {
+	_, err := u.dbpool.Exec(ctx, insertUserSQL, login, passwordHash)
 	if err != nil {
 		return fmt.Errorf("ошибка при сохранении пользователя: %w", err)
 	}
 
 	return nil
 }
+
+func (u *User) Block(ctx context.Context, userID int, reason string) error {
+	_, err := u.dbpool.Exec(ctx, blockUserSQL, userID, reason)
+	if err != nil {
+		return fmt.Errorf("ошибка при блокировке пользователя: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) Unblock(ctx context.Context, userID int) error {
+	_, err := u.dbpool.Exec(ctx, unblockUserSQL, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка при разблокировке пользователя: %w", err)
+	}
+
+	return nil
+}
+
+func (u *User) SetRole(ctx context.Context, login string, role string) (bool, error) {
+	tag, err := u.dbpool.Exec(ctx, updateUserRoleSQL, login, role)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при изменении роли пользователя: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
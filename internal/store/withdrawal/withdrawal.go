@@ -4,84 +4,52 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
-	"go.uber.org/fx"
 	"go.uber.org/zap"
 
 	"github.com/dontagr/loyalty/internal/faultTolerance/pgretry"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
 	"github.com/dontagr/loyalty/internal/store/models"
 )
 
 const (
-	searchTotalWithdrawalSQL = `SELECT SUM(withdrawal) FROM public.withdrawal WHERE user_id=$1`
-	insertWithdrawalSQL      = `INSERT INTO public.withdrawal (id, user_id, withdrawal) VALUES ($1, $2, $3);`
-	searchWithdrawalSQL      = `SELECT id, user_id, withdrawal, create_dt FROM public.withdrawal WHERE id=$1`
-	listWithdrawalSQL        = `SELECT id, user_id, withdrawal, create_dt  FROM public.withdrawal WHERE user_id = $1 ORDER BY create_dt DESC`
-	decreaseUserBalanceSQL   = `UPDATE public.user SET balance=balance-$1 WHERE ID=$2`
-	createWithdrawalTable    = `
-CREATE TABLE IF NOT EXISTS public."withdrawal" (
-	id bigint NOT NULL,
-	user_id bigint NOT NULL,
-	withdrawal bigint DEFAULT NUll,
-	create_dt timestamptz DEFAULT NOW() NOT NULL,
-	CONSTRAINT withdrawal_pk PRIMARY KEY (id),
-	CONSTRAINT withdrawal_id_idx UNIQUE (user_id,id)
-);
-`
+	searchTotalWithdrawalSQL     = `SELECT SUM(withdrawal) FROM public.withdrawal WHERE user_id=$1 AND status <> 'REVERSED'`
+	insertWithdrawalSQL          = `INSERT INTO public.withdrawal (id, user_id, withdrawal) VALUES ($1, $2, $3);`
+	searchWithdrawalSQL          = `SELECT id, user_id, withdrawal, status, status_dt, reversal_reason, create_dt FROM public.withdrawal WHERE id=$1`
+	searchWithdrawalForUpdateSQL = `SELECT id, user_id, withdrawal, status, status_dt, reversal_reason, create_dt FROM public.withdrawal WHERE id=$1 FOR UPDATE`
+	updateStatusSQL              = `UPDATE public.withdrawal SET status=$1, status_dt=NOW(), reversal_reason=$2 WHERE id=$3 RETURNING status_dt`
+	listWithdrawalSQL            = `SELECT id, user_id, withdrawal, status, status_dt, reversal_reason, create_dt  FROM public.withdrawal WHERE user_id = $1 ORDER BY create_dt DESC`
+	listWithdrawalPageSQL        = `SELECT id, user_id, withdrawal, status, status_dt, reversal_reason, create_dt FROM public.withdrawal WHERE user_id = $1`
+	searchPeriodTotalSQL         = `SELECT COALESCE(SUM(withdrawal), 0) FROM public.withdrawal WHERE user_id = $1 AND status <> 'REVERSED' AND ($2::timestamptz IS NULL OR create_dt >= $2) AND ($3::timestamptz IS NULL OR create_dt < $3)`
 )
 
 type Withdrawal struct {
 	dbpool *pgretry.PgxRetry
+	ledger interfaces.LedgerStore
 	log    *zap.SugaredLogger
 }
 
-func NewWithdrawal(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry, lc fx.Lifecycle) *Withdrawal {
+func NewWithdrawal(log *zap.SugaredLogger, dbpool *pgretry.PgxRetry, ledger interfaces.LedgerStore) *Withdrawal {
 	withdrawal := Withdrawal{
 		dbpool: dbpool,
+		ledger: ledger,
 		log:    log,
 	}
 
-	lc.Append(fx.Hook{
-		OnStart: func(ctx context.Context) error {
-			return withdrawal.addShema(ctx)
-		},
-	})
-
 	return &withdrawal
 }
 
-func (w *Withdrawal) addShema(ctx context.Context) error {
-	_, err := w.dbpool.Exec(ctx, createWithdrawalTable)
-
-	return err
-}
-
-func (w *Withdrawal) BeginTX() (pgx.Tx, error) {
-	tx, txErr := w.dbpool.Begin(context.Background())
-	if txErr != nil {
-		return nil, fmt.Errorf("ошибка начала транзакции: %w", txErr)
-	}
-
-	return tx, nil
-}
-
-func (w *Withdrawal) RollbackTX(tx pgx.Tx) {
-	if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
-		w.log.Errorf("ошибка отката транзакции: %v", rollbackErr)
-	}
+func (w *Withdrawal) RunInTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return w.dbpool.RunInTx(ctx, fn)
 }
 
-func (w *Withdrawal) CommitTX(tx pgx.Tx) {
-	if commitErr := tx.Commit(context.Background()); commitErr != nil {
-		w.log.Errorf("ошибка при коммите транзакции: %v", commitErr)
-	}
-}
-
-func (w *Withdrawal) GetTotalWithdrawal(userID int) (float64, error) {
+func (w *Withdrawal) GetTotalWithdrawal(ctx context.Context, userID int) (float64, error) {
 	var withdrawal float64
 	var withdrawalInt int
-	err := w.dbpool.QueryRow(context.Background(), searchTotalWithdrawalSQL, userID).Scan(&withdrawalInt)
+	err := w.dbpool.QueryRow(ctx, searchTotalWithdrawalSQL, userID).Scan(&withdrawalInt)
 	if errors.Is(err, pgx.ErrNoRows) || withdrawalInt == 0 {
 		return 0, nil
 	}
@@ -93,14 +61,9 @@ func (w *Withdrawal) GetTotalWithdrawal(userID int) (float64, error) {
 	return withdrawal, nil
 }
 
-func (w *Withdrawal) GetWithdraw(orderID string) (*models.Withdrawal, error) {
-	var withdrawal models.Withdrawal
-	err := w.dbpool.QueryRow(context.Background(), searchWithdrawalSQL, orderID).Scan(
-		&withdrawal.ID,
-		&withdrawal.UserID,
-		&withdrawal.Withdrawal,
-		&withdrawal.CreateDateTime,
-	)
+func (w *Withdrawal) GetWithdraw(ctx context.Context, orderID string) (*models.Withdrawal, error) {
+	withdrawal := new(models.Withdrawal)
+	err := scanWithdrawal(w.dbpool.QueryRow(ctx, searchWithdrawalSQL, orderID), withdrawal)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return &models.Withdrawal{}, nil
 	}
@@ -108,24 +71,66 @@ func (w *Withdrawal) GetWithdraw(orderID string) (*models.Withdrawal, error) {
 		return nil, err
 	}
 
-	return &withdrawal, nil
+	return withdrawal, nil
 }
 
-func (w *Withdrawal) SaveWithdraw(tx pgx.Tx, withdrawal models.Withdrawal) error {
-	_, err := tx.Exec(context.Background(), insertWithdrawalSQL, withdrawal.ID, withdrawal.UserID, withdrawal.Withdrawal)
+// GetTxWithdraw блокирует списание до конца транзакции, чтобы смена статуса и возврат баллов не гонялись.
+func (w *Withdrawal) GetTxWithdraw(ctx context.Context, tx pgx.Tx, orderID string) (*models.Withdrawal, error) {
+	withdrawal := new(models.Withdrawal)
+	err := scanWithdrawal(tx.QueryRow(ctx, searchWithdrawalForUpdateSQL, orderID), withdrawal)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &models.Withdrawal{}, nil
+	}
 	if err != nil {
-		return fmt.Errorf("ошибка при создания списания: %w", err)
+		return nil, fmt.Errorf("ошибка при поиске списания: %w", err)
 	}
-	_, err = tx.Exec(context.Background(), decreaseUserBalanceSQL, withdrawal.Withdrawal, withdrawal.UserID)
+
+	return withdrawal, nil
+}
+
+func (w *Withdrawal) UpdateStatusTx(ctx context.Context, tx pgx.Tx, withdrawal *models.Withdrawal) error {
+	err := tx.QueryRow(ctx, updateStatusSQL, withdrawal.Status, withdrawal.ReversalReason, withdrawal.ID).Scan(&withdrawal.StatusDateTime)
 	if err != nil {
-		return fmt.Errorf("ошибка при обновлении пользователя: %w", err)
+		return fmt.Errorf("ошибка при смене статуса списания: %w", err)
 	}
 
 	return nil
 }
 
-func (w *Withdrawal) GetWithdrawalListByUserID(userID int) ([]*models.Withdrawal, error) {
-	rows, err := w.dbpool.Query(context.Background(), listWithdrawalSQL, userID)
+// ReverseTx отменяет списание и возвращает баллы проводкой REVERSAL в той же транзакции.
+func (w *Withdrawal) ReverseTx(ctx context.Context, tx pgx.Tx, withdrawal *models.Withdrawal) error {
+	withdrawal.Status = models.WithdrawalReversed
+	if err := w.UpdateStatusTx(ctx, tx, withdrawal); err != nil {
+		return err
+	}
+
+	return w.ledger.PostTx(ctx, tx, &models.LedgerEntry{
+		UserID:        withdrawal.UserID,
+		EntryType:     models.LedgerReversal,
+		ContraAccount: models.ContraWithdrawal,
+		Amount:        withdrawal.Withdrawal,
+		WithdrawalID:  &withdrawal.ID,
+		Reason:        withdrawal.ReversalReason,
+	})
+}
+
+func (w *Withdrawal) SaveWithdraw(ctx context.Context, tx pgx.Tx, withdrawal models.Withdrawal) error {
+	_, err := tx.Exec(ctx, insertWithdrawalSQL, withdrawal.ID, withdrawal.UserID, withdrawal.Withdrawal)
+	if err != nil {
+		return fmt.Errorf("ошибка при создания списания: %w", err)
+	}
+
+	return w.ledger.PostTx(ctx, tx, &models.LedgerEntry{
+		UserID:        withdrawal.UserID,
+		EntryType:     models.LedgerWithdrawal,
+		ContraAccount: models.ContraWithdrawal,
+		Amount:        -withdrawal.Withdrawal,
+		WithdrawalID:  &withdrawal.ID,
+	})
+}
+
+func (w *Withdrawal) GetWithdrawalListByUserID(ctx context.Context, userID int) ([]*models.Withdrawal, error) {
+	rows, err := w.dbpool.Query(ctx, listWithdrawalSQL, userID)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при извлечении списаний: %w", err)
 	}
@@ -134,7 +139,46 @@ func (w *Withdrawal) GetWithdrawalListByUserID(userID int) ([]*models.Withdrawal
 	var result []*models.Withdrawal
 	for rows.Next() {
 		withdrawal := new(models.Withdrawal)
-		err := rows.Scan(&withdrawal.ID, &withdrawal.UserID, &withdrawal.Withdrawal, &withdrawal.CreateDateTime)
+		err := scanWithdrawal(rows, withdrawal)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании списания: %w", err)
+		}
+
+		result = append(result, withdrawal)
+	}
+
+	return result, nil
+}
+
+func (w *Withdrawal) GetWithdrawalPageByUserID(ctx context.Context, userID int, filter *models.WithdrawalFilter) ([]*models.Withdrawal, error) {
+	query := strings.Builder{}
+	query.WriteString(listWithdrawalPageSQL)
+	args := []any{userID}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		fmt.Fprintf(&query, " AND create_dt >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		fmt.Fprintf(&query, " AND create_dt < $%d", len(args))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.Time, filter.After.ID)
+		fmt.Fprintf(&query, " AND (create_dt, id) < ($%d, $%d::bigint)", len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	fmt.Fprintf(&query, " ORDER BY create_dt DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := w.dbpool.Query(ctx, query.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при извлечении списаний: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Withdrawal, 0, filter.Limit)
+	for rows.Next() {
+		withdrawal := new(models.Withdrawal)
+		err := scanWithdrawal(rows, withdrawal)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка при сканировании списания: %w", err)
 		}
@@ -144,3 +188,25 @@ func (w *Withdrawal) GetWithdrawalListByUserID(userID int) ([]*models.Withdrawal
 
 	return result, nil
 }
+
+func (w *Withdrawal) GetPeriodTotal(ctx context.Context, userID int, from *time.Time, to *time.Time) (int, error) {
+	var total int
+	err := w.dbpool.QueryRow(ctx, searchPeriodTotalSQL, userID, from, to).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при подсчете списаний: %w", err)
+	}
+
+	return total, nil
+}
+
+func scanWithdrawal(row pgx.Row, withdrawal *models.Withdrawal) error {
+	return row.Scan(
+		&withdrawal.ID,
+		&withdrawal.UserID,
+		&withdrawal.Withdrawal,
+		&withdrawal.Status,
+		&withdrawal.StatusDateTime,
+		&withdrawal.ReversalReason,
+		&withdrawal.CreateDateTime,
+	)
+}
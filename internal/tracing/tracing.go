@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+
+	defaultServiceName = "gophermart"
+	traceParentHeader  = "traceparent"
+)
+
+// NewTracerProvider настраивает глобальный провайдер и W3C propagator, поэтому компоненты получают трейсер
+// через otel.Tracer и не зависят от того, включена ли трассировка.
+func NewTracerProvider(cfg *config.Config, log *zap.SugaredLogger, lc fx.Lifecycle) (trace.TracerProvider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Tracing.Exporter {
+	case ExporterOTLP:
+		options := []otlptracehttp.Option{}
+		if cfg.Tracing.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(cfg.Tracing.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		provider := noop.NewTracerProvider()
+		otel.SetTracerProvider(provider)
+
+		return provider, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Tracing.Exporter, err)
+	}
+
+	sampleRatio := cfg.Tracing.SampleRatio
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName(cfg.Tracing)))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Infof("tracing enabled, exporter: %s", cfg.Tracing.Exporter)
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return provider.Shutdown(ctx)
+		},
+	})
+
+	return provider, nil
+}
+
+func ServiceName(cfg config.Tracing) string {
+	if cfg.ServiceName == "" {
+		return defaultServiceName
+	}
+
+	return cfg.ServiceName
+}
+
+// TraceParent возвращает W3C traceparent текущего спана, чтобы сохранить его вместе с данными
+// и связать с ним последующую фоновую обработку.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	return carrier.Get(traceParentHeader)
+}
+
+// LinkFromTraceParent строит ссылку на спан по сохраненному traceparent, для пустого или
+// некорректного значения возвращает false.
+func LinkFromTraceParent(traceParent string) (trace.Link, bool) {
+	if traceParent == "" {
+		return trace.Link{}, false
+	}
+
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{traceParentHeader: traceParent})
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return trace.Link{}, false
+	}
+
+	return trace.Link{SpanContext: spanContext}, true
+}
+
+// RecordError отмечает спан как ошибочный, nil игнорируется.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+)
+
+const (
+	defaultAuditCleanupInterval = time.Hour
+	defaultAuditCleanupBatch    = 1000
+)
+
+// AuditCleaner удаляет записи журнала аудита старше срока хранения. Удаление идет пачками,
+// чтобы не держать долгую блокировку на таблице при первом запуске на большом журнале.
+type AuditCleaner struct {
+	log             *zap.SugaredLogger
+	retention       time.Duration
+	interval        time.Duration
+	batch           int
+	shutdownTimeout time.Duration
+	cancel          context.CancelFunc
+	done            chan struct{}
+	store           interfaces.AuditStore
+}
+
+func NewAuditCleaner(cfg *config.Config, store interfaces.AuditStore, log *zap.SugaredLogger, lc fx.Lifecycle) *AuditCleaner {
+	a := &AuditCleaner{
+		log:             log,
+		retention:       time.Duration(cfg.Audit.Retention) * 24 * time.Hour,
+		interval:        time.Duration(cfg.Audit.CleanupInterval) * time.Second,
+		batch:           cfg.Audit.CleanupBatch,
+		shutdownTimeout: time.Duration(cfg.Service.ShutdownTimeout) * time.Second,
+		done:            make(chan struct{}),
+		store:           store,
+	}
+	if a.interval <= 0 {
+		a.interval = defaultAuditCleanupInterval
+	}
+	if a.batch <= 0 {
+		a.batch = defaultAuditCleanupBatch
+	}
+	if a.shutdownTimeout <= 0 {
+		a.shutdownTimeout = defaultShutdownTimeout
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			if a.retention <= 0 {
+				log.Infof("audit cleanup disabled, retention is not set")
+				return nil
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			a.cancel = cancel
+			go a.Handle(ctx)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return stopLoop(ctx, "audit cleaner", a.cancel, a.done, a.shutdownTimeout)
+		},
+	})
+
+	return a
+}
+
+func (a *AuditCleaner) Handle(ctx context.Context) {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			a.log.Infof("audit cleaner stopped")
+			return
+		case <-ticker.C:
+		}
+
+		before := time.Now().Add(-a.retention)
+		var total int64
+		for {
+			deleted, err := a.store.DeleteExpired(ctx, before, a.batch)
+			if err != nil {
+				a.log.Errorf("audit cleanup failed: %v", err)
+				break
+			}
+			total += deleted
+			if deleted < int64(a.batch) {
+				break
+			}
+		}
+		if total > 0 {
+			a.log.Infof("audit cleanup removed %d entries older than %s", total, before.Format(time.RFC3339))
+		}
+	}
+}
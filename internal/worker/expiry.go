@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/ledger"
+)
+
+const defaultExpiryInterval = 24 * time.Hour
+
+// Expirer раз в ExpiryInterval (по умолчанию раз в сутки) сжигает баллы с истекшим сроком действия.
+type Expirer struct {
+	log             *zap.SugaredLogger
+	interval        time.Duration
+	shutdownTimeout time.Duration
+	cancel          context.CancelFunc
+	done            chan struct{}
+	service         *ledger.Service
+}
+
+func NewExpirer(cfg *config.Config, service *ledger.Service, log *zap.SugaredLogger, lc fx.Lifecycle) *Expirer {
+	e := &Expirer{
+		log:             log,
+		interval:        time.Duration(cfg.Points.ExpiryInterval) * time.Second,
+		shutdownTimeout: time.Duration(cfg.Service.ShutdownTimeout) * time.Second,
+		done:            make(chan struct{}),
+		service:         service,
+	}
+	if e.interval <= 0 {
+		e.interval = defaultExpiryInterval
+	}
+	if e.shutdownTimeout <= 0 {
+		e.shutdownTimeout = defaultShutdownTimeout
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			e.cancel = cancel
+			go e.Handle(ctx)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return stopLoop(ctx, "expirer", e.cancel, e.done, e.shutdownTimeout)
+		},
+	})
+
+	return e
+}
+
+func (e *Expirer) Handle(ctx context.Context) {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.log.Infof("expirer stopped")
+			return
+		case <-ticker.C:
+		}
+
+		users, total, err := e.service.ExpirePoints(ctx)
+		if err != nil {
+			e.log.Errorf("points expiry failed: %v", err)
+			continue
+		}
+		if users > 0 {
+			e.log.Infof("points expiry finished: users=%d amount=%d", users, total)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// stopLoop отменяет контекст цикла воркера и ждет его завершения не дольше timeout.
+// cancel равен nil, если цикл не запускался.
+func stopLoop(ctx context.Context, name string, cancel context.CancelFunc, done <-chan struct{}, timeout time.Duration) error {
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	ctx, stop := context.WithTimeout(ctx, timeout)
+	defer stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s stop: %w", name, ctx.Err())
+	}
+}
@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/ratelimit"
+)
+
+const defaultRateLimitCleanupInterval = time.Hour
+
+// RateLimitCleaner удаляет устаревшие неудачные попытки входа и истекшие блокировки,
+// чтобы перебор с постоянно меняющихся адресов не раздувал таблицы ограничения попыток.
+type RateLimitCleaner struct {
+	log             *zap.SugaredLogger
+	interval        time.Duration
+	shutdownTimeout time.Duration
+	cancel          context.CancelFunc
+	done            chan struct{}
+	limiter         *ratelimit.LoginLimiter
+}
+
+func NewRateLimitCleaner(cfg *config.Config, limiter *ratelimit.LoginLimiter, log *zap.SugaredLogger, lc fx.Lifecycle) *RateLimitCleaner {
+	r := &RateLimitCleaner{
+		log:             log,
+		interval:        time.Duration(cfg.RateLimit.CleanupInterval) * time.Second,
+		shutdownTimeout: time.Duration(cfg.Service.ShutdownTimeout) * time.Second,
+		done:            make(chan struct{}),
+		limiter:         limiter,
+	}
+	if r.interval <= 0 {
+		r.interval = defaultRateLimitCleanupInterval
+	}
+	if r.shutdownTimeout <= 0 {
+		r.shutdownTimeout = defaultShutdownTimeout
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			r.cancel = cancel
+			go r.Handle(ctx)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return stopLoop(ctx, "rate limit cleaner", r.cancel, r.done, r.shutdownTimeout)
+		},
+	})
+
+	return r
+}
+
+func (r *RateLimitCleaner) Handle(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Infof("rate limit cleaner stopped")
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := r.limiter.Cleanup(ctx)
+		if err != nil {
+			r.log.Errorf("rate limit cleanup failed: %v", err)
+			continue
+		}
+		if deleted > 0 {
+			r.log.Infof("rate limit cleanup removed %d rows", deleted)
+		}
+	}
+}
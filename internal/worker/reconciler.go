@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/metrics"
+	"github.com/dontagr/loyalty/internal/service/reconciliation"
+)
+
+type Reconciler struct {
+	log             *zap.SugaredLogger
+	interval        time.Duration
+	autoCorrect     bool
+	shutdownTimeout time.Duration
+	cancel          context.CancelFunc
+	done            chan struct{}
+	service         *reconciliation.Service
+	metrics         *metrics.Metrics
+}
+
+func NewReconciler(cfg *config.Config, service *reconciliation.Service, m *metrics.Metrics, log *zap.SugaredLogger, lc fx.Lifecycle) *Reconciler {
+	r := &Reconciler{
+		log:             log,
+		interval:        time.Duration(cfg.Reconciliation.Interval) * time.Second,
+		autoCorrect:     cfg.Reconciliation.AutoCorrect,
+		shutdownTimeout: time.Duration(cfg.Service.ShutdownTimeout) * time.Second,
+		done:            make(chan struct{}),
+		service:         service,
+		metrics:         m,
+	}
+	if r.shutdownTimeout <= 0 {
+		r.shutdownTimeout = defaultShutdownTimeout
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			if r.interval <= 0 {
+				log.Infof("reconciliation worker disabled")
+				return nil
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			r.cancel = cancel
+			go r.Handle(ctx)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return stopLoop(ctx, "reconciler", r.cancel, r.done, r.shutdownTimeout)
+		},
+	})
+
+	return r
+}
+
+func (r *Reconciler) Handle(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Infof("reconciler stopped")
+			return
+		case <-ticker.C:
+		}
+
+		run, err := r.service.Run(ctx, r.autoCorrect)
+		if err != nil {
+			r.log.Errorf("reconciliation failed: %v", err)
+			continue
+		}
+
+		r.metrics.ReconciliationFinished(len(run.Discrepancies), run.Corrected)
+		for _, d := range run.Discrepancies {
+			r.log.Warnw("balance discrepancy", "run", run.ID, "user", d.UserID, "balance", d.Balance, "expected", d.Expected, "corrected", d.Corrected)
+		}
+		r.log.Infof("reconciliation run %d finished: users=%d discrepancies=%d corrected=%d", run.ID, run.UsersChecked, len(run.Discrepancies), run.Corrected)
+	}
+}
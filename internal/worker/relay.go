@@ -0,0 +1,163 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/interfaces"
+	"github.com/dontagr/loyalty/internal/service/publisher"
+	"github.com/dontagr/loyalty/internal/store/models"
+)
+
+const (
+	maxRelayBackoff        = time.Hour
+	defaultRelayInterval   = 5 * time.Second
+	defaultRelayBatchSize  = 100
+	defaultRelayAttempts   = 10
+	defaultRelayTimeout    = 5 * time.Second
+	defaultRelayRetryDelay = 5 * time.Second
+)
+
+type Relay struct {
+	log             *zap.SugaredLogger
+	interval        time.Duration
+	batchSize       int
+	maxAttempts     int
+	retryInterval   time.Duration
+	timeout         time.Duration
+	leaseTTL        time.Duration
+	owner           string
+	shutdownTimeout time.Duration
+	cancel          context.CancelFunc
+	done            chan struct{}
+	store           interfaces.OutboxStore
+	publisher       publisher.Publisher
+}
+
+func NewRelay(cfg *config.Config, store interfaces.OutboxStore, publisher publisher.Publisher, log *zap.SugaredLogger, lc fx.Lifecycle) *Relay {
+	r := &Relay{
+		log:             log,
+		interval:        time.Duration(cfg.Outbox.Interval) * time.Second,
+		batchSize:       cfg.Outbox.BatchSize,
+		maxAttempts:     cfg.Outbox.MaxAttempts,
+		retryInterval:   time.Duration(cfg.Outbox.RetryInterval) * time.Second,
+		timeout:         time.Duration(cfg.Outbox.Timeout) * time.Second,
+		leaseTTL:        time.Duration(cfg.Outbox.LeaseTTL) * time.Second,
+		owner:           newLeaseOwner(),
+		shutdownTimeout: time.Duration(cfg.Service.ShutdownTimeout) * time.Second,
+		done:            make(chan struct{}),
+		store:           store,
+		publisher:       publisher,
+	}
+	if r.interval <= 0 {
+		r.interval = defaultRelayInterval
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = defaultRelayBatchSize
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = defaultRelayAttempts
+	}
+	if r.retryInterval <= 0 {
+		r.retryInterval = defaultRelayRetryDelay
+	}
+	if r.timeout <= 0 {
+		r.timeout = defaultRelayTimeout
+	}
+	if r.leaseTTL <= 0 {
+		r.leaseTTL = defaultLeaseTTL
+	}
+	if r.shutdownTimeout <= 0 {
+		r.shutdownTimeout = defaultShutdownTimeout
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			r.cancel = cancel
+			go r.Handle(ctx)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return stopLoop(ctx, "relay", r.cancel, r.done, r.shutdownTimeout)
+		},
+	})
+
+	return r
+}
+
+func (r *Relay) Handle(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Infof("relay stopped")
+			return
+		case <-ticker.C:
+		}
+
+		events, err := r.store.ClaimPending(ctx, r.owner, r.leaseTTL, r.batchSize)
+		if err != nil {
+			r.log.Errorf("failed to get outbox events: %v", err)
+			continue
+		}
+
+		for _, event := range events {
+			// неотправленные события из пачки заберет другая реплика после истечения аренды
+			if ctx.Err() != nil {
+				break
+			}
+			r.publish(ctx, event)
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, event *models.OutboxEvent) {
+	publishCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	err := r.publisher.Publish(publishCtx, event)
+	// отправку прервала остановка, а не получатель: попытку не засчитываем
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	// результат отправки сохраняем даже во время остановки, иначе событие уйдет повторно
+	storeCtx, storeCancel := context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+	defer storeCancel()
+	if err == nil {
+		if err := r.store.MarkPublished(storeCtx, event.ID); err != nil {
+			r.log.Errorf("failed to mark outbox event %d as published: %v", event.ID, err)
+		}
+		return
+	}
+
+	event.Attempts++
+	if event.Attempts >= r.maxAttempts {
+		event.Status = models.OutboxDead
+		r.log.Errorf("outbox event %d moved to dead letter after %d attempts: %v", event.ID, event.Attempts, err)
+	} else {
+		r.log.Warnf("outbox event %d publish attempt %d failed: %v", event.ID, event.Attempts, err)
+	}
+
+	if err := r.store.MarkFailed(storeCtx, event, err.Error(), time.Now().Add(r.backoff(event.Attempts))); err != nil {
+		r.log.Errorf("failed to save outbox event %d retry state: %v", event.ID, err)
+	}
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.retryInterval
+	for i := 1; i < attempts && delay < maxRelayBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRelayBackoff)
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/service/tier"
+)
+
+const defaultTierInterval = 24 * time.Hour
+
+// TierEvaluator раз в TierInterval (по умолчанию раз в сутки) пересчитывает уровни пользователей.
+type TierEvaluator struct {
+	log             *zap.SugaredLogger
+	interval        time.Duration
+	shutdownTimeout time.Duration
+	cancel          context.CancelFunc
+	done            chan struct{}
+	service         *tier.Service
+}
+
+func NewTierEvaluator(cfg *config.Config, service *tier.Service, log *zap.SugaredLogger, lc fx.Lifecycle) *TierEvaluator {
+	e := &TierEvaluator{
+		log:             log,
+		interval:        time.Duration(cfg.Loyalty.TierInterval) * time.Second,
+		shutdownTimeout: time.Duration(cfg.Service.ShutdownTimeout) * time.Second,
+		done:            make(chan struct{}),
+		service:         service,
+	}
+	if e.interval <= 0 {
+		e.interval = defaultTierInterval
+	}
+	if e.shutdownTimeout <= 0 {
+		e.shutdownTimeout = defaultShutdownTimeout
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			e.cancel = cancel
+			go e.Handle(ctx)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return stopLoop(ctx, "tier evaluator", e.cancel, e.done, e.shutdownTimeout)
+		},
+	})
+
+	return e
+}
+
+func (e *TierEvaluator) Handle(ctx context.Context) {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.log.Infof("tier evaluator stopped")
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := e.service.Reevaluate(ctx)
+		if err != nil {
+			e.log.Errorf("tier evaluation failed: %v", err)
+			continue
+		}
+		if changed > 0 {
+			e.log.Infof("tier evaluation finished: changed=%d", changed)
+		}
+	}
+}
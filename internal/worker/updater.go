@@ -2,92 +2,225 @@ package worker
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
 	"github.com/dontagr/loyalty/internal/config"
+	"github.com/dontagr/loyalty/internal/metrics"
+	"github.com/dontagr/loyalty/internal/service/audit"
 	"github.com/dontagr/loyalty/internal/service/interfaces"
 	"github.com/dontagr/loyalty/internal/service/transport"
 	"github.com/dontagr/loyalty/internal/store/models"
+	"github.com/dontagr/loyalty/internal/tracing"
 )
 
+const (
+	defaultUpdaterBatchSize = 100
+	defaultRetryMaxInterval = time.Hour
+	defaultLeaseTTL         = 5 * time.Minute
+	defaultShutdownTimeout  = 10 * time.Second
+	storeTimeout            = 5 * time.Second
+)
+
+var tracer = otel.Tracer("github.com/dontagr/loyalty/internal/worker")
+
 type Updater struct {
-	cfg       *config.Config
-	log       *zap.SugaredLogger
-	workers   int
-	interval  int
-	store     interfaces.OrderStore
-	transport transport.Transport
+	cfg               *config.Config
+	log               *zap.SugaredLogger
+	workers           int
+	interval          int
+	batchSize         int
+	retryBaseInterval time.Duration
+	retryMaxInterval  time.Duration
+	maxAge            time.Duration
+	leaseTTL          time.Duration
+	owner             string
+	shutdownTimeout   time.Duration
+	cancel            context.CancelFunc
+	done              chan struct{}
+	lastTick          atomic.Int64
+	store             interfaces.OrderStore
+	transport         transport.Transport
+	metrics           *metrics.Metrics
+	auditService      *audit.Service
 }
 
-func NewUpdater(cfg *config.Config, store interfaces.OrderStore, transport *transport.HTTPManager, log *zap.SugaredLogger, lc fx.Lifecycle) *Updater {
+func NewUpdater(cfg *config.Config, store interfaces.OrderStore, transport *transport.HTTPManager, m *metrics.Metrics, auditService *audit.Service, log *zap.SugaredLogger, lc fx.Lifecycle) *Updater {
 	u := &Updater{
-		cfg:       cfg,
-		log:       log,
-		workers:   cfg.Service.WorkerLimit,
-		interval:  cfg.Service.UpdaterInterval,
-		store:     store,
-		transport: transport,
+		cfg:               cfg,
+		log:               log,
+		workers:           cfg.Service.WorkerLimit,
+		interval:          cfg.Service.UpdaterInterval,
+		batchSize:         cfg.Service.BatchSize,
+		retryBaseInterval: time.Duration(cfg.Service.RetryBaseInterval) * time.Second,
+		retryMaxInterval:  time.Duration(cfg.Service.RetryMaxInterval) * time.Second,
+		maxAge:            time.Duration(cfg.Service.OrderMaxAge) * time.Second,
+		leaseTTL:          time.Duration(cfg.Service.LeaseTTL) * time.Second,
+		owner:             newLeaseOwner(),
+		shutdownTimeout:   time.Duration(cfg.Service.ShutdownTimeout) * time.Second,
+		done:              make(chan struct{}),
+		store:             store,
+		transport:         transport,
+		metrics:           m,
+		auditService:      auditService,
+	}
+	if u.batchSize <= 0 {
+		u.batchSize = defaultUpdaterBatchSize
+	}
+	if u.retryBaseInterval <= 0 {
+		u.retryBaseInterval = time.Duration(u.interval) * time.Second
+	}
+	if u.retryMaxInterval <= 0 {
+		u.retryMaxInterval = defaultRetryMaxInterval
+	}
+	if u.leaseTTL <= 0 {
+		u.leaseTTL = defaultLeaseTTL
+	}
+	if u.shutdownTimeout <= 0 {
+		u.shutdownTimeout = defaultShutdownTimeout
 	}
 
 	lc.Append(fx.Hook{
 		OnStart: func(_ context.Context) error {
-			go u.Handle()
+			ctx, cancel := context.WithCancel(context.Background())
+			u.cancel = cancel
+			u.lastTick.Store(time.Now().UnixNano())
+			go u.Handle(ctx)
 
 			return nil
 		},
+		OnStop: func(ctx context.Context) error {
+			return stopLoop(ctx, "updater", u.cancel, u.done, u.shutdownTimeout)
+		},
 	})
 
 	return u
 }
 
-func (upd *Updater) Handle() {
+func (upd *Updater) Handle(ctx context.Context) {
+	defer close(upd.done)
+
 	jobs := make(chan *models.Order, upd.workers)
+	wg := sync.WaitGroup{}
 	for w := 1; w <= upd.workers; w++ {
-		go upd.worker(w, jobs)
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			upd.worker(ctx, w, jobs)
+		}(w)
 	}
 
+	ticker := time.NewTicker(time.Duration(upd.interval) * time.Second)
+	defer ticker.Stop()
 	for {
-		time.Sleep(time.Duration(upd.interval) * time.Second)
-		upd.log.Infof("start planing")
+		select {
+		case <-ctx.Done():
+			upd.log.Infof("updater stopping, waiting for workers")
+			close(jobs)
+			wg.Wait()
+			upd.log.Infof("updater stopped")
+
+			return
+		case <-ticker.C:
+		}
+
+		upd.plan(ctx, jobs)
+		upd.lastTick.Store(time.Now().UnixNano())
+	}
+}
+
+// LastTick возвращает время последнего завершенного цикла планирования, до первого цикла - время запуска.
+func (upd *Updater) LastTick() time.Time {
+	return time.Unix(0, upd.lastTick.Load())
+}
 
-		processing, err := upd.store.GetListForProcessing()
+func (upd *Updater) plan(ctx context.Context, jobs chan *models.Order) {
+	upd.log.Infof("start planing")
+
+	if upd.maxAge > 0 {
+		stalled, err := upd.store.MarkStalled(ctx, time.Now().Add(-upd.maxAge))
 		if err != nil {
-			upd.log.Errorf("failed to get order list: %v", err)
-			continue
+			upd.log.Errorf("failed to mark stalled orders: %v", err)
+		} else if stalled > 0 {
+			upd.log.Warnf("%d orders marked as stalled for manual review", stalled)
 		}
+	}
 
-		upd.log.Infof("start send")
-		for _, order := range processing {
-			jobs <- order
+	processing, err := upd.store.ClaimForProcessing(ctx, upd.owner, upd.leaseTTL, upd.batchSize)
+	if err != nil {
+		upd.log.Errorf("failed to get order list: %v", err)
+		return
+	}
+
+	upd.log.Infof("start send")
+	for i, order := range processing {
+		select {
+		case jobs <- order:
+			upd.metrics.SetQueueDepth(len(jobs))
+		case <-ctx.Done():
+			for _, row := range processing[i:] {
+				upd.releaseLease(ctx, row, 0)
+			}
+			return
 		}
-		upd.log.Infof("finish send")
 	}
+	upd.log.Infof("finish send")
 }
 
-func (upd *Updater) worker(w int, jobs chan *models.Order) {
+func (upd *Updater) worker(ctx context.Context, w int, jobs chan *models.Order) {
 	upd.log.Infof("worker %d runing", w)
 	for row := range jobs {
-		upd.orderProcess(row, w)
+		upd.metrics.SetQueueDepth(len(jobs))
+		// после остановки оставшиеся в очереди заказы не опрашиваем, а сразу отдаем другим репликам
+		if ctx.Err() != nil {
+			upd.releaseLease(ctx, row, w)
+			continue
+		}
+		upd.orderProcess(ctx, row, w)
 	}
 }
 
-func (upd *Updater) orderProcess(row *models.Order, w int) {
-	if !upd.store.BlockOrder(row.ID) {
-		return
+func (upd *Updater) orderProcess(ctx context.Context, row *models.Order, w int) {
+	defer upd.releaseLease(ctx, row, w)
+
+	// опрос идет в отдельной трассе, связанной ссылкой с запросом, которым пользователь загрузил заказ
+	options := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithAttributes(attribute.String("order.id", row.ID), attribute.Int("order.attempt", row.AttemptCount), attribute.Int("worker", w)),
 	}
-	defer upd.store.UnblockOrder(row.ID)
+	if link, ok := tracing.LinkFromTraceParent(row.TraceParent); ok {
+		options = append(options, trace.WithLinks(link))
+	}
+	ctx, span := tracer.Start(ctx, "updater.orderProcess", options...)
+	defer span.End()
 
-	request, err := upd.transport.NewRequest(row.ID, w)
+	request, err := upd.transport.NewRequest(ctx, row.ID, w)
 	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
 		upd.log.Errorf("worker %d request orderID:%s error code:%d message:%s : %v", w, row.ID, err.Code, err.Message, err.Err)
 
+		// 429 говорит о перегрузке системы расчета, а не о заказе: лимитер уже поставил опрос на паузу
 		if err.Code == http.StatusTooManyRequests {
-			time.Sleep(time.Duration(60) * time.Second)
+			upd.metrics.OrderPolled("RATE_LIMITED")
+			return
 		}
+		upd.metrics.OrderPolled("ERROR")
+		upd.scheduleNextAttempt(ctx, row, w)
 		return
 	}
 
@@ -95,12 +228,72 @@ func (upd *Updater) orderProcess(row *models.Order, w int) {
 	order := &models.Order{ID: row.ID, Accrual: &accrual}
 	order.SetStatusFromStr(request.Status)
 
+	span.SetAttributes(attribute.String("order.status", order.Status.String()))
 	if order.Status == models.StatusNew {
+		upd.metrics.OrderPolled(order.Status.String())
+		upd.scheduleNextAttempt(ctx, row, w)
 		return
 	}
 
-	er := upd.store.UpdateOrder(order)
+	// ответ системы расчета уже получен, поэтому сохраняем его даже во время остановки
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+	defer cancel()
+	// аудит пишется в транзакции начисления и только если баллы начислены. Начисление делает система,
+	// поэтому исполнитель не указывается
+	credited, er := upd.store.UpdateOrder(storeCtx, order, func(tx pgx.Tx, amount int) error {
+		return upd.auditService.RecordTx(storeCtx, tx, &models.AuditEntry{Action: audit.ActionAccrualCredit, Target: row.ID, Amount: &amount})
+	})
 	if er != nil {
 		upd.log.Errorf("worker %d update failed: %v", w, er)
+		tracing.RecordError(span, er)
+		upd.metrics.OrderPolled("ERROR")
+	} else {
+		upd.metrics.OrderPolled(order.Status.String())
+		if credited > 0 {
+			// в счетчик попадает и надбавка уровня, проведенная вместе с начислением
+			upd.metrics.PointsAccrued(credited)
+		}
+	}
+	if er != nil || order.Status == models.StatusProcessing {
+		upd.scheduleNextAttempt(ctx, row, w)
 	}
 }
+
+func (upd *Updater) releaseLease(ctx context.Context, row *models.Order, w int) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+	defer cancel()
+
+	if err := upd.store.ReleaseLease(ctx, row.ID, upd.owner); err != nil {
+		upd.log.Errorf("worker %d failed to release orderID:%s: %v", w, row.ID, err)
+	}
+}
+
+func (upd *Updater) scheduleNextAttempt(ctx context.Context, row *models.Order, w int) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+	defer cancel()
+
+	err := upd.store.ScheduleNextAttempt(ctx, row.ID, time.Now().Add(upd.backoff(row.AttemptCount)))
+	if err != nil {
+		upd.log.Errorf("worker %d failed to schedule orderID:%s: %v", w, row.ID, err)
+	}
+}
+
+func (upd *Updater) backoff(attempts int) time.Duration {
+	delay := upd.retryBaseInterval
+	for i := 0; i < attempts && delay < upd.retryMaxInterval; i++ {
+		delay *= 2
+	}
+
+	return min(delay, upd.retryMaxInterval)
+}
+
+func newLeaseOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "gophermart"
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}